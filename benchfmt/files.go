@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 )
 
 // A Files reads benchmark results from a sequence of input files.
@@ -42,6 +43,19 @@ type Files struct {
 	// override .file.
 	AllowLabels bool
 
+	// Parallelism is the maximum number of files to parse
+	// concurrently. If Parallelism is 0 or 1, files are read one
+	// at a time by a single Reader.
+	//
+	// Regardless of Parallelism, Scan returns Records in the
+	// same order: all records from each file in order, followed
+	// by the records from the next file. However, with
+	// Parallelism > 1, files are read ahead of the caller, so an
+	// error in a later file may not be reported until the caller
+	// has consumed all of the preceding records. Callers that stop
+	// calling Scan before it returns false should call Close.
+	Parallelism int
+
 	// inputs is the sequence of remaining inputs, or nil if this
 	// Files has not started yet. Note that this distinguishes nil
 	// from length 0.
@@ -51,6 +65,10 @@ type Files struct {
 	file    *os.File
 	isStdin bool
 	err     error
+
+	// par is the state of parallel reading, or nil if reading
+	// sequentially.
+	par *parallelFiles
 }
 
 type input struct {
@@ -102,6 +120,11 @@ func (f *Files) init() {
 		inp.label = fmt.Sprintf("%s#%d", inp.path, pathI[inp.path])
 		pathI[inp.path]++
 	}
+
+	if f.Parallelism > 1 {
+		f.par = newParallelFiles(f.inputs, f.Parallelism)
+		f.inputs = f.inputs[:0]
+	}
 }
 
 // Scan advances the reader to the next result in the sequence of
@@ -117,6 +140,9 @@ func (f *Files) Scan() bool {
 	if f.inputs == nil {
 		f.init()
 	}
+	if f.par != nil {
+		return f.scanParallel()
+	}
 
 	for {
 		if f.file == nil {
@@ -168,6 +194,12 @@ func (f *Files) Scan() bool {
 // Result returns the record that was just read by Scan.
 // See Reader.Result.
 func (f *Files) Result() Record {
+	if f.par != nil {
+		if f.par.rec == nil {
+			return noResult
+		}
+		return f.par.rec
+	}
 	return f.reader.Result()
 }
 
@@ -181,5 +213,216 @@ func (f *Files) Err() error {
 // Units returns the accumulated unit metadata.
 // See Reader.Units.
 func (f *Files) Units() map[UnitMetadataKey]*UnitMetadata {
+	if f.par != nil {
+		return f.par.units
+	}
 	return f.reader.Units()
 }
+
+// Close stops reading f and releases any resources it holds. After
+// Close, Scan returns false and Err returns any error that was
+// already reported. It is not necessary to call Close if Scan
+// has returned false, but it is necessary to avoid leaking goroutines
+// if Parallelism > 1 and the caller stops reading early.
+func (f *Files) Close() {
+	if f.par != nil {
+		f.par.stop()
+	} else if f.file != nil && !f.isStdin {
+		f.file.Close()
+	}
+	f.file = nil
+	f.inputs = []input{}
+}
+
+// parallelBatch is the number of records a background file reader
+// sends to Scan at a time.
+const parallelBatch = 256
+
+// parallelFiles is the state of a Files with Parallelism > 1.
+//
+// Each input is parsed by a separate goroutine using its own Reader.
+// These goroutines clone each Result and send batches of records on
+// the per-input channel in outs, which Scan drains in input order.
+// At most Parallelism inputs are being read at once.
+type parallelFiles struct {
+	outs []chan fileBatch
+	done chan struct{}
+	once sync.Once
+
+	batch []Record
+	pos   int
+	rec   Record
+
+	// units is the unit metadata merged across all files consumed so
+	// far. Each file's Reader has its own unit metadata, so Scan
+	// reconciles them in input order, exactly as a single Reader
+	// would.
+	units UnitMetadataMap
+}
+
+// A fileBatch is a batch of records read from a single input. If err
+// is non-nil, it is the last batch sent for that input.
+type fileBatch struct {
+	recs []Record
+	err  error
+}
+
+func newParallelFiles(inputs []input, parallelism int) *parallelFiles {
+	p := &parallelFiles{
+		outs:  make([]chan fileBatch, len(inputs)),
+		done:  make(chan struct{}),
+		units: make(UnitMetadataMap),
+	}
+	for i := range p.outs {
+		// Allow readers to get a few batches ahead of Scan.
+		p.outs[i] = make(chan fileBatch, 4)
+	}
+
+	// Scan consumes p.outs, so give the dispatcher its own copies.
+	inputs = append([]input(nil), inputs...)
+	outs := append([]chan fileBatch(nil), p.outs...)
+	go func() {
+		// sem limits the number of active readers. Slots are
+		// acquired in input order, so the reader for the input Scan
+		// is waiting on always has a slot.
+		sem := make(chan struct{}, parallelism)
+		// Reads of stdin are serialized in input order, since
+		// they share a single stream.
+		var prevStdin chan struct{}
+		for i, inp := range inputs {
+			select {
+			case sem <- struct{}{}:
+			case <-p.done:
+				return
+			}
+			var wait, stdinDone chan struct{}
+			if inp.isStdin {
+				wait, stdinDone = prevStdin, make(chan struct{})
+				prevStdin = stdinDone
+			}
+			go func(inp input, out chan<- fileBatch) {
+				defer func() { <-sem }()
+				if stdinDone != nil {
+					defer close(stdinDone)
+				}
+				if wait != nil {
+					select {
+					case <-wait:
+					case <-p.done:
+						return
+					}
+				}
+				p.read(inp, out)
+			}(inp, outs[i])
+		}
+	}()
+	return p
+}
+
+// read parses inp and sends its records to out.
+func (p *parallelFiles) read(inp input, out chan<- fileBatch) {
+	defer close(out)
+	send := func(b fileBatch) bool {
+		select {
+		case out <- b:
+			return true
+		case <-p.done:
+			return false
+		}
+	}
+
+	file := os.Stdin
+	if !inp.isStdin {
+		var err error
+		file, err = os.Open(inp.path)
+		if err != nil {
+			send(fileBatch{err: err})
+			return
+		}
+		defer file.Close()
+	}
+
+	var r Reader
+	r.Reset(file, inp.path, ".file", inp.label)
+	batch := make([]Record, 0, parallelBatch)
+	for r.Scan() {
+		rec := r.Result()
+		if res, ok := rec.(*Result); ok {
+			// The Reader will overwrite res.
+			rec = res.Clone()
+		}
+		batch = append(batch, rec)
+		if len(batch) == cap(batch) {
+			if !send(fileBatch{recs: batch}) {
+				return
+			}
+			batch = make([]Record, 0, parallelBatch)
+		}
+	}
+	if len(batch) > 0 {
+		if !send(fileBatch{recs: batch}) {
+			return
+		}
+	}
+	if err := r.Err(); err != nil {
+		send(fileBatch{err: err})
+	}
+}
+
+// stop stops all background readers.
+func (p *parallelFiles) stop() {
+	p.once.Do(func() { close(p.done) })
+	p.outs, p.batch, p.rec = nil, nil, nil
+}
+
+// scanParallel implements Scan for Parallelism > 1.
+func (f *Files) scanParallel() bool {
+	p := f.par
+	for {
+		for p.pos < len(p.batch) {
+			rec := p.batch[p.pos]
+			p.pos++
+			if m, ok := rec.(*UnitMetadata); ok {
+				rec = p.mergeUnit(m)
+				if rec == nil {
+					continue
+				}
+			}
+			p.rec = rec
+			return true
+		}
+
+		if len(p.outs) == 0 {
+			// We're out of files.
+			p.stop()
+			return false
+		}
+		b, ok := <-p.outs[0]
+		if !ok {
+			// Done with this file.
+			p.outs = p.outs[1:]
+			continue
+		}
+		if b.err != nil {
+			f.err = b.err
+			p.stop()
+			return false
+		}
+		p.batch, p.pos = b.recs, 0
+	}
+}
+
+// mergeUnit merges m into the accumulated unit metadata. It returns
+// the Record Scan should return for m, or nil if m should be skipped.
+// This mirrors Reader.parseUnitLine.
+func (p *parallelFiles) mergeUnit(m *UnitMetadata) Record {
+	if have, ok := p.units[m.UnitMetadataKey]; ok {
+		if have.Value == m.Value {
+			// We already have this unit metadata. Ignore.
+			return nil
+		}
+		return &SyntaxError{m.fileName, m.line, fmt.Sprintf("metadata %s of unit %s already set to %s", m.Key, m.OrigUnit, have.Value)}
+	}
+	p.units[m.UnitMetadataKey] = m
+	return m
+}
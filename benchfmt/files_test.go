@@ -6,10 +6,14 @@ package benchfmt
 
 import (
 	"errors"
+	"fmt"
 	"io/fs"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestFiles(t *testing.T) {
@@ -131,6 +135,103 @@ func TestFiles(t *testing.T) {
 	)
 }
 
+func TestFilesParallel(t *testing.T) {
+	paths, err := filepath.Glob("testdata/bent/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Read some files more than once to exercise disambiguation and
+	// unit metadata merging.
+	paths = append(paths, paths[:3]...)
+
+	readAll := func(f *Files) (string, error) {
+		var buf strings.Builder
+		for f.Scan() {
+			rec := f.Result()
+			fileName, line := rec.Pos()
+			fmt.Fprintf(&buf, "%s:%d: ", fileName, line)
+			printRecord(&buf, rec)
+		}
+		units := make([]string, 0, len(f.Units()))
+		for k, v := range f.Units() {
+			units = append(units, fmt.Sprintf("%v=%s", k, v.Value))
+		}
+		sort.Strings(units)
+		fmt.Fprintf(&buf, "units: %v\n", units)
+		return buf.String(), f.Err()
+	}
+
+	want, err := readAll(&Files{Paths: paths})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, par := range []int{2, 4, 64} {
+		got, err := readAll(&Files{Paths: paths, Parallelism: par})
+		if err != nil {
+			t.Fatalf("Parallelism=%d: %s", par, err)
+		}
+		if got != want {
+			t.Errorf("Parallelism=%d: records differ from sequential read", par)
+		}
+	}
+
+	// Errors are reported in order.
+	f := &Files{Paths: []string{"testdata/files/a", "testdata/files/c", "testdata/files/b"}, Parallelism: 4}
+	var names []string
+	for f.Scan() {
+		if res, ok := f.Result().(*Result); ok {
+			names = append(names, string(res.Name))
+		}
+	}
+	if !errors.Is(f.Err(), fs.ErrNotExist) {
+		t.Errorf("got error %v, want ErrNotExist", f.Err())
+	}
+	if got := strings.Join(names, " "); got != "X Y" {
+		t.Errorf("got %s, want X Y", got)
+	}
+
+	// Stopping early and closing.
+	f = &Files{Paths: paths, Parallelism: 4}
+	if !f.Scan() {
+		t.Fatal("Scan failed")
+	}
+	f.Close()
+	if f.Scan() {
+		t.Errorf("Scan succeeded after Close")
+	}
+}
+
+func BenchmarkFiles(b *testing.B) {
+	paths, err := filepath.Glob("testdata/bent/*")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, par := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("parallelism=%d", par), func(b *testing.B) {
+			start := time.Now()
+			var n int
+			for i := 0; i < b.N; i++ {
+				f := &Files{Paths: paths, Parallelism: par}
+				for f.Scan() {
+					n++
+					if err, ok := f.Result().(error); ok {
+						b.Fatal("malformed record: ", err)
+					}
+				}
+				if err := f.Err(); err != nil {
+					b.Fatal(err)
+				}
+			}
+			dur := time.Since(start)
+
+			b.StopTimer()
+			b.ReportMetric(float64(n/b.N), "records/op")
+			b.ReportMetric(float64(n)*float64(time.Second)/float64(dur), "records/sec")
+		})
+	}
+}
+
 func fakeStdin(content string, cb func()) {
 	r, w, err := os.Pipe()
 	if err != nil {
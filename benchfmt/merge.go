@@ -0,0 +1,478 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchfmt
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// A Merger merges and sorts benchmark results from any number of
+// sources, such as several Readers.
+//
+// A Merger orders Results by their file configuration and then by
+// benchmark name. Results with the same file configuration and name
+// retain the order they were added in. This groups together Results
+// with identical configuration, so a Writer will emit each distinct
+// configuration block only once.
+//
+// A Merger buffers Results in memory until they exceed MaxMemory, at
+// which point it sorts them and spills them to a temporary file. Once
+// all Results have been added, Scan merges the in-memory Results and
+// all spilled files. Hence, a Merger can sort inputs much larger than
+// memory. To bound the number of open files, once there are
+// maxMergeRuns spilled files, the Merger merges them into one.
+//
+// A Merger's API for retrieving results is modeled on Reader. Callers
+// should Add all records, and then call Scan, Result, and Err. Once
+// Scan has been called, Add must not be called. If the caller stops
+// calling Scan before it returns false, it should call Close to remove
+// any temporary files.
+type Merger struct {
+	// MaxMemory is the approximate number of bytes of Results to
+	// buffer in memory before spilling them to a temporary file.
+	// If MaxMemory is 0, it defaults to DefaultMergeMemory.
+	MaxMemory int
+
+	// TempDir is the directory for temporary files. If TempDir is
+	// "", it uses the default directory for temporary files (see
+	// os.TempDir).
+	TempDir string
+
+	// Dedup indicates that Results that are identical to another
+	// Result should be dropped. Two Results are identical if they
+	// have the same file configuration, name, iteration count, and
+	// measurements; internal configuration such as ".file" is not
+	// considered. If Dedup is set, Results with the same file
+	// configuration and name are ordered by iteration count and
+	// measurements rather than the order they were added in.
+	Dedup bool
+
+	mem   []*mergeEntry
+	size  int
+	seq   int64
+	runs  []*os.File
+	units UnitMetadataMap
+
+	// Fields below here are used once Scan has been called.
+	started bool
+	unitQ   []Record
+	srcs    mergeHeap
+	last    *mergeEntry
+	rec     Record
+	err     error
+}
+
+// DefaultMergeMemory is the default value of Merger.MaxMemory.
+const DefaultMergeMemory = 256 << 20
+
+// maxMergeRuns is the number of spilled files at which a Merger
+// merges them into one. It's a variable for testing.
+var maxMergeRuns = 32
+
+// A mergeEntry is a single Result with its sort key. Its fields are
+// exported for encoding/gob.
+type mergeEntry struct {
+	// Config is the canonical encoding of the Result's file
+	// configuration.
+	Config string
+	// Seq is the order this entry was added in.
+	Seq    int64
+	Result *Result
+	// File and Line are the position of Result, which isn't
+	// otherwise encoded because its fields are unexported.
+	File string
+	Line int
+}
+
+// Add adds rec to the set of records to merge. If rec is a *Result,
+// Add makes a copy of it, so the caller may reuse it. If rec is a
+// *UnitMetadata, the Merger will return it before any Results. If it
+// conflicts with earlier unit metadata, the earlier metadata is kept
+// and Add returns a *SyntaxError at rec's position, like Reader does
+// for conflicting metadata within a file; this error is not sticky,
+// so the caller may report it and keep adding records. Add ignores
+// *SyntaxError records.
+func (m *Merger) Add(rec Record) error {
+	if m.started {
+		panic("Merger.Add called after Merger.Scan")
+	}
+	if m.err != nil {
+		return m.err
+	}
+	switch rec := rec.(type) {
+	case *Result:
+		e := &mergeEntry{Config: mergeConfigKey(rec), Seq: m.seq, Result: rec.Clone()}
+		m.seq++
+		m.mem = append(m.mem, e)
+		m.size += e.size()
+		max := m.MaxMemory
+		if max == 0 {
+			max = DefaultMergeMemory
+		}
+		if m.size >= max {
+			if err := m.spill(); err != nil {
+				m.err = err
+				m.Close()
+				return err
+			}
+		}
+	case *UnitMetadata:
+		if m.units == nil {
+			m.units = make(UnitMetadataMap)
+		}
+		have, ok := m.units[rec.UnitMetadataKey]
+		if !ok {
+			m.units[rec.UnitMetadataKey] = rec
+			m.unitQ = append(m.unitQ, rec)
+		} else if have.Value != rec.Value {
+			fileName, line := rec.Pos()
+			return &SyntaxError{fileName, line, fmt.Sprintf("metadata %s of unit %s already set to %s", rec.Key, rec.OrigUnit, have.Value)}
+		}
+	}
+	return nil
+}
+
+// mergeConfigKey returns a string that is equal for Results with the
+// same file configuration and that sorts the same as the sorted file
+// configuration.
+func mergeConfigKey(res *Result) string {
+	var keys []int
+	for i, cfg := range res.Config {
+		if cfg.File {
+			keys = append(keys, i)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return res.Config[keys[i]].Key < res.Config[keys[j]].Key
+	})
+	var buf strings.Builder
+	for _, i := range keys {
+		cfg := &res.Config[i]
+		// Keys can't contain spaces and lines can't contain
+		// newlines, so this is unambiguous.
+		buf.WriteString(cfg.Key)
+		buf.WriteByte(' ')
+		buf.Write(cfg.Value)
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+// size returns the approximate memory footprint of e.
+func (e *mergeEntry) size() int {
+	const overhead = 128
+	n := overhead + len(e.Config) + len(e.Result.Name) + 48*len(e.Result.Values)
+	for _, cfg := range e.Result.Config {
+		n += 48 + len(cfg.Key) + len(cfg.Value)
+	}
+	return n
+}
+
+// less reports whether a sorts before b.
+func (m *Merger) less(a, b *mergeEntry) bool {
+	if a.Config != b.Config {
+		return a.Config < b.Config
+	}
+	if c := bytes.Compare(a.Result.Name, b.Result.Name); c != 0 {
+		return c < 0
+	}
+	if m.Dedup {
+		if c := compareMeasurements(a.Result, b.Result); c != 0 {
+			return c < 0
+		}
+	}
+	return a.Seq < b.Seq
+}
+
+// compareMeasurements compares the iteration counts and Values of a
+// and b.
+func compareMeasurements(a, b *Result) int {
+	if a.Iters != b.Iters {
+		if a.Iters < b.Iters {
+			return -1
+		}
+		return 1
+	}
+	for i := 0; i < len(a.Values) && i < len(b.Values); i++ {
+		av, bv := &a.Values[i], &b.Values[i]
+		if av.Unit != bv.Unit {
+			return strings.Compare(av.Unit, bv.Unit)
+		}
+		if av.Value != bv.Value {
+			if av.Value < bv.Value {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a.Values) - len(b.Values)
+}
+
+// spill sorts the in-memory entries and writes them to a temporary
+// file. If that makes maxMergeRuns files, it merges them into one.
+func (m *Merger) spill() error {
+	m.sortMem()
+	h := &mergeHeap{srcs: []*mergeSource{{mem: m.mem}}, less: m.less}
+	if err := h.init(); err != nil {
+		return err
+	}
+	if err := m.writeRun(h); err != nil {
+		return err
+	}
+	m.mem, m.size = nil, 0
+
+	if len(m.runs) < maxMergeRuns {
+		return nil
+	}
+	var runs mergeHeap
+	if err := m.openRuns(&runs); err != nil {
+		return err
+	}
+	old := m.runs
+	if err := m.writeRun(&runs); err != nil {
+		return err
+	}
+	m.runs = m.runs[len(old):]
+	var errs []error
+	for _, f := range old {
+		f.Close()
+		if err := os.Remove(f.Name()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeRun writes the merged entries of h to a new temporary file and
+// adds it to m.runs.
+func (m *Merger) writeRun(h *mergeHeap) error {
+	f, err := os.CreateTemp(m.TempDir, "benchmerge-")
+	if err != nil {
+		return err
+	}
+	m.runs = append(m.runs, f)
+	bw := bufio.NewWriter(f)
+	enc := gob.NewEncoder(bw)
+	for len(h.srcs) > 0 {
+		e, err := h.next()
+		if err != nil {
+			return err
+		}
+		e.File, e.Line = e.Result.Pos()
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// openRuns adds a source for each spilled file to h and loads their
+// first entries.
+func (m *Merger) openRuns(h *mergeHeap) error {
+	h.less = m.less
+	for _, f := range m.runs {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		h.srcs = append(h.srcs, &mergeSource{dec: gob.NewDecoder(bufio.NewReader(f))})
+	}
+	return h.init()
+}
+
+func (m *Merger) sortMem() {
+	sort.Slice(m.mem, func(i, j int) bool {
+		return m.less(m.mem[i], m.mem[j])
+	})
+}
+
+// Scan advances to the next record in the merged sequence and reports
+// whether a record was read. The caller should use the Result method
+// to get the record. If Scan reaches the end of the sequence, or if an
+// I/O error occurs, it returns false, in which case the caller should
+// use the Err method to check for errors.
+func (m *Merger) Scan() bool {
+	if m.err != nil {
+		return false
+	}
+	if !m.started {
+		m.started = true
+		if err := m.start(); err != nil {
+			m.err = err
+			m.Close()
+			return false
+		}
+	}
+
+	// Unit metadata comes first.
+	if len(m.unitQ) > 0 {
+		m.rec, m.unitQ = m.unitQ[0], m.unitQ[1:]
+		return true
+	}
+
+	for len(m.srcs.srcs) > 0 {
+		e, err := m.srcs.next()
+		if err != nil {
+			m.err = err
+			m.Close()
+			return false
+		}
+
+		if m.Dedup && m.last != nil && m.last.Config == e.Config && bytes.Equal(m.last.Result.Name, e.Result.Name) && compareMeasurements(m.last.Result, e.Result) == 0 {
+			// Duplicate of the previous Result.
+			continue
+		}
+		m.last = e
+		m.rec = e.Result
+		return true
+	}
+	m.Close()
+	return false
+}
+
+// start prepares the sources to merge.
+func (m *Merger) start() error {
+	m.srcs.less = m.less
+	if len(m.runs) == 0 {
+		// Everything fit in memory.
+		m.sortMem()
+	} else if len(m.mem) > 0 {
+		if err := m.spill(); err != nil {
+			return err
+		}
+	}
+	if len(m.mem) > 0 {
+		m.srcs.srcs = append(m.srcs.srcs, &mergeSource{mem: m.mem})
+		m.mem = nil
+	}
+	return m.openRuns(&m.srcs)
+}
+
+// Result returns the record that was just read by Scan. This is either
+// a *Result or a *UnitMetadata. Unlike Reader, the Merger does not
+// reuse Results, so the caller may retain them.
+func (m *Merger) Result() Record {
+	if m.rec == nil {
+		return noResult
+	}
+	return m.rec
+}
+
+// Err returns the first error encountered by the Merger, either while
+// spilling results in Add or while merging them in Scan.
+func (m *Merger) Err() error {
+	return m.err
+}
+
+// Units returns the accumulated unit metadata.
+func (m *Merger) Units() UnitMetadataMap {
+	return m.units
+}
+
+// Close removes any temporary files created by m. After Close, Scan
+// returns false.
+func (m *Merger) Close() error {
+	var errs []error
+	for _, f := range m.runs {
+		f.Close()
+		if err := os.Remove(f.Name()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	m.runs = nil
+	m.mem = nil
+	m.srcs.srcs = nil
+	m.unitQ = nil
+	return errors.Join(errs...)
+}
+
+// A mergeSource is a sorted sequence of entries, either in memory or
+// in a spilled file.
+type mergeSource struct {
+	cur *mergeEntry
+
+	mem []*mergeEntry
+	dec *gob.Decoder
+}
+
+// next advances s to its next entry, setting s.cur to nil at the end.
+func (s *mergeSource) next() error {
+	if s.dec == nil {
+		if len(s.mem) == 0 {
+			s.cur = nil
+		} else {
+			s.cur, s.mem = s.mem[0], s.mem[1:]
+		}
+		return nil
+	}
+	e := new(mergeEntry)
+	if err := s.dec.Decode(e); err == io.EOF {
+		s.cur = nil
+		return nil
+	} else if err != nil {
+		return err
+	}
+	e.Result.fileName, e.Result.line = e.File, e.Line
+	s.cur = e
+	return nil
+}
+
+// mergeHeap is a heap of mergeSources ordered by their current entry.
+type mergeHeap struct {
+	srcs []*mergeSource
+	less func(a, b *mergeEntry) bool
+}
+
+// init loads the first entry of each source in h, drops empty
+// sources, and establishes the heap order.
+func (h *mergeHeap) init() error {
+	srcs := h.srcs[:0]
+	for _, src := range h.srcs {
+		if src.cur == nil {
+			if err := src.next(); err != nil {
+				return err
+			}
+		}
+		if src.cur != nil {
+			srcs = append(srcs, src)
+		}
+	}
+	h.srcs = srcs
+	heap.Init(h)
+	return nil
+}
+
+// next removes and returns the least entry in h.
+func (h *mergeHeap) next() (*mergeEntry, error) {
+	src := h.srcs[0]
+	e := src.cur
+	if err := src.next(); err != nil {
+		return nil, err
+	}
+	if src.cur == nil {
+		heap.Pop(h)
+	} else {
+		heap.Fix(h, 0)
+	}
+	return e, nil
+}
+
+func (h *mergeHeap) Len() int           { return len(h.srcs) }
+func (h *mergeHeap) Less(i, j int) bool { return h.less(h.srcs[i].cur, h.srcs[j].cur) }
+func (h *mergeHeap) Swap(i, j int)      { h.srcs[i], h.srcs[j] = h.srcs[j], h.srcs[i] }
+func (h *mergeHeap) Push(x any)         { h.srcs = append(h.srcs, x.(*mergeSource)) }
+func (h *mergeHeap) Pop() any {
+	x := h.srcs[len(h.srcs)-1]
+	h.srcs = h.srcs[:len(h.srcs)-1]
+	return x
+}
@@ -0,0 +1,158 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchfmt
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMerger(t *testing.T) {
+	inputs := []string{`
+Unit ns/op a=1
+a: 1
+b: 1
+BenchmarkB 1 1 ns/op
+BenchmarkA 1 2 ns/op
+a: 2
+BenchmarkA 1 3 ns/op
+`, `
+Unit ns/op a=2
+Unit B/op b=1
+b: 1
+a: 1
+BenchmarkA 1 2 ns/op
+BenchmarkA 1 4 ns/op
+BenchmarkC 1 5 ns/op
+`}
+
+	check := func(t *testing.T, m *Merger, want string) {
+		t.Helper()
+		var errs []string
+		for i, input := range inputs {
+			r := new(Reader)
+			r.Reset(strings.NewReader(input), "test", ".file", string(rune('0'+i)))
+			for r.Scan() {
+				if err := m.Add(r.Result()); err != nil {
+					errs = append(errs, err.Error())
+				}
+			}
+		}
+		// The second input's conflicting unit metadata is
+		// reported and dropped.
+		if want := []string{"test:2: metadata a of unit ns/op already set to 1"}; !reflect.DeepEqual(errs, want) {
+			t.Errorf("got Add errors %q, want %q", errs, want)
+		}
+		var out strings.Builder
+		w := NewWriterOptions(&out, WriterOptions{IgnoreInternalConfig: true})
+		for m.Scan() {
+			if err := w.Write(m.Result()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := m.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != want {
+			t.Errorf("want:\n%sgot:\n%s", want, out.String())
+		}
+	}
+
+	const want = `Unit ns/op a=1
+Unit B/op b=1
+a: 1
+b: 1
+
+BenchmarkA 1 2 ns/op
+BenchmarkA 1 2 ns/op
+BenchmarkA 1 4 ns/op
+BenchmarkB 1 1 ns/op
+BenchmarkC 1 5 ns/op
+
+a: 2
+
+BenchmarkA 1 3 ns/op
+`
+	const wantDedup = `Unit ns/op a=1
+Unit B/op b=1
+a: 1
+b: 1
+
+BenchmarkA 1 2 ns/op
+BenchmarkA 1 4 ns/op
+BenchmarkB 1 1 ns/op
+BenchmarkC 1 5 ns/op
+
+a: 2
+
+BenchmarkA 1 3 ns/op
+`
+
+	t.Run("memory", func(t *testing.T) {
+		check(t, &Merger{}, want)
+	})
+	t.Run("dedup", func(t *testing.T) {
+		check(t, &Merger{Dedup: true}, wantDedup)
+	})
+	t.Run("spill", func(t *testing.T) {
+		// Force every result to spill.
+		dir := t.TempDir()
+		m := &Merger{MaxMemory: 1, TempDir: dir}
+		check(t, m, want)
+		ents, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ents) != 0 {
+			t.Errorf("temporary files not removed: %v", ents)
+		}
+	})
+	t.Run("spill dedup", func(t *testing.T) {
+		check(t, &Merger{MaxMemory: 500, TempDir: t.TempDir(), Dedup: true}, wantDedup)
+	})
+	t.Run("fan-in", func(t *testing.T) {
+		// Spilled files are merged to stay under maxMergeRuns.
+		defer func(n int) { maxMergeRuns = n }(maxMergeRuns)
+		maxMergeRuns = 3
+		dir := t.TempDir()
+		m := &Merger{MaxMemory: 1, TempDir: dir}
+		for i, input := range inputs {
+			r := new(Reader)
+			r.Reset(strings.NewReader(input), "test"+string(rune('0'+i)))
+			for r.Scan() {
+				m.Add(r.Result())
+				if ents, _ := os.ReadDir(dir); len(ents) >= maxMergeRuns {
+					t.Fatalf("got %d spilled files, want < %d", len(ents), maxMergeRuns)
+				}
+			}
+		}
+		var out strings.Builder
+		w := NewWriterOptions(&out, WriterOptions{IgnoreInternalConfig: true})
+		var pos []string
+		for m.Scan() {
+			if res, ok := m.Result().(*Result); ok {
+				fileName, line := res.Pos()
+				pos = append(pos, fmt.Sprintf("%s:%d", fileName, line))
+			}
+			if err := w.Write(m.Result()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := m.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != want {
+			t.Errorf("want:\n%sgot:\n%s", want, out.String())
+		}
+		// Positions survive spilling.
+		wantPos := []string{"test0:6", "test1:6", "test1:7", "test0:5", "test1:8", "test0:8"}
+		if !reflect.DeepEqual(pos, wantPos) {
+			t.Errorf("got positions %v, want %v", pos, wantPos)
+		}
+	})
+}
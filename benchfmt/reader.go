@@ -40,6 +40,10 @@ type Reader struct {
 	units  UnitMetadataMap
 
 	interns map[string]string
+
+	// ignored, if non-nil, is called with each line that the
+	// Reader ignores. It may queue records in r.q.
+	ignored func(r *Reader, line []byte)
 }
 
 // A SyntaxError represents a syntax error on a particular line of a
@@ -181,6 +185,9 @@ func (r *Reader) Scan() bool {
 			continue
 		}
 		// Ignore the line.
+		if r.ignored != nil {
+			r.ignored(r, line)
+		}
 	}
 
 	if len(r.q) > 0 {
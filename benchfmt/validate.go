@@ -0,0 +1,207 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchfmt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/perf/benchunit"
+)
+
+// Validate reads the Go benchmark format from r and checks it
+// strictly against the format specification. It returns all syntax
+// errors the Reader would report, plus the following problems that
+// the Reader tolerates:
+//
+// - Lines that appear to be configuration lines, but whose key does
+// not begin with a lower-case letter, contains spaces or upper-case
+// letters, or isn't followed by a space.
+//
+// - Benchmark lines with an iteration count that isn't positive, or
+// with more than one measurement in the same unit.
+//
+// - Units that look like a number (which usually indicates a missing
+// unit), that have no denominator such as "/op", that are a standard
+// unit spelled in a different case, such as "NS/op", that are scaled
+// but won't be tidied (see benchunit.Tidy), such as "ms/op", or that
+// differ only in case from an earlier unit.
+//
+// - Runs of a benchmark whose set of file configuration keys differs
+// from an earlier run of the same benchmark, which usually means the
+// results aren't comparable.
+//
+// - Unit metadata with a key other than "better" or "assume", or with
+// a value other than "higher" or "lower" for "better", or "nothing" or
+// "exact" for "assume".
+//
+// fileName is used in error messages. The returned errors are in
+// order of their position. Validate returns a non-nil error only if
+// reading r fails.
+func Validate(r io.Reader, fileName string) ([]*SyntaxError, error) {
+	var errs []*SyntaxError
+	reader := NewReader(r, fileName)
+	reader.ignored = validateIgnoredLine
+
+	// keySets records the file configuration keys of the first
+	// run of each benchmark, and the line of that run.
+	type keySet struct {
+		keys string
+		line int
+	}
+	keySets := make(map[string]keySet)
+
+	// units records the first unit seen in each case-folded form,
+	// and the line it was on.
+	type unitLine struct {
+		unit string
+		line int
+	}
+	units := make(map[string]unitLine)
+
+	for reader.Scan() {
+		switch rec := reader.Result().(type) {
+		case *SyntaxError:
+			errs = append(errs, rec)
+
+		case *Result:
+			errorf := func(format string, args ...any) {
+				errs = append(errs, &SyntaxError{rec.fileName, rec.line, fmt.Sprintf(format, args...)})
+			}
+			if rec.Iters <= 0 {
+				errorf("iteration count must be positive")
+			}
+			for i, val := range rec.Values {
+				unit := val.Unit
+				if val.OrigUnit != "" {
+					unit = val.OrigUnit
+				}
+				if msg := validateUnit(unit); msg != "" {
+					errorf("%s", msg)
+				} else if prev, ok := units[strings.ToLower(unit)]; !ok {
+					units[strings.ToLower(unit)] = unitLine{unit, rec.line}
+				} else if prev.unit != unit {
+					errorf("unit %q differs only in case from %q on line %d", unit, prev.unit, prev.line)
+				}
+				for _, prev := range rec.Values[:i] {
+					if prev.Unit == val.Unit {
+						errorf("duplicate measurements in unit %s", val.Unit)
+						break
+					}
+				}
+			}
+
+			keys := validateKeySet(rec)
+			name := string(rec.Name)
+			if prev, ok := keySets[name]; !ok {
+				keySets[name] = keySet{keys, rec.line}
+			} else if prev.keys != keys {
+				errorf("file configuration keys of Benchmark%s differ from run on line %d: have [%s], had [%s]", name, prev.line, keys, prev.keys)
+			}
+
+		case *UnitMetadata:
+			errorf := func(format string, args ...any) {
+				errs = append(errs, &SyntaxError{rec.fileName, rec.line, fmt.Sprintf(format, args...)})
+			}
+			switch rec.Key {
+			case "better":
+				if rec.Value != "higher" && rec.Value != "lower" {
+					errorf("unit metadata better=%s must be higher or lower", rec.Value)
+				}
+			case "assume":
+				if rec.Value != "nothing" && rec.Value != "exact" {
+					errorf("unit metadata assume=%s must be nothing or exact", rec.Value)
+				}
+			default:
+				errorf("unknown unit metadata key %q", rec.Key)
+			}
+		}
+	}
+	return errs, reader.Err()
+}
+
+// standardUnits are the units reported by the testing package, and
+// their tidied forms.
+var standardUnits = []string{"ns/op", "sec/op", "B/op", "allocs/op", "MB/s", "B/s"}
+
+// scaledUnits are unit numerators that are scaled times or sizes that
+// benchunit.Tidy doesn't convert to base units, and what to use
+// instead.
+var scaledUnits = map[string]string{
+	"s": "ns or sec", "us": "ns or sec", "µs": "ns or sec", "μs": "ns or sec", "ms": "ns or sec",
+	"kB": "B or MB", "KB": "B or MB", "KiB": "B or MB", "MiB": "B or MB", "GB": "B or MB", "GiB": "B or MB",
+}
+
+// validateUnit returns the first problem with unit, or "".
+func validateUnit(unit string) string {
+	if _, err := strconv.ParseFloat(unit, 64); err == nil {
+		return fmt.Sprintf("unit %q looks like a number", unit)
+	}
+	for _, std := range standardUnits {
+		if unit != std && strings.EqualFold(unit, std) {
+			return fmt.Sprintf("unit %q should be spelled %q", unit, std)
+		}
+	}
+	numer, _, ok := strings.Cut(unit, "/")
+	if !ok {
+		return fmt.Sprintf("unit %q has no denominator, such as /op", unit)
+	}
+	if use, ok := scaledUnits[numer]; ok {
+		if _, tidied := benchunit.Tidy(1, unit); tidied == unit {
+			return fmt.Sprintf("unit %q is scaled but won't be tidied; use %s", unit, use)
+		}
+	}
+	return ""
+}
+
+// validateKeySet returns the sorted file configuration keys of res.
+func validateKeySet(res *Result) string {
+	var keys []string
+	for _, cfg := range res.Config {
+		if cfg.File {
+			keys = append(keys, cfg.Key)
+		}
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, " ")
+}
+
+// validateIgnoredLine checks whether line, which the Reader ignored,
+// was likely meant to be a configuration line.
+func validateIgnoredLine(r *Reader, line []byte) {
+	colon := bytes.IndexByte(line, ':')
+	if colon <= 0 {
+		return
+	}
+	key := line[:colon]
+	// Only consider lines that start with a letter. This skips
+	// things like "--- FAIL: TestX" and indented log output.
+	first, _ := utf8.DecodeRune(key)
+	if !unicode.IsLetter(first) {
+		return
+	}
+	if bytes.HasPrefix(line[colon:], []byte("://")) {
+		// Probably a URL.
+		return
+	}
+	switch {
+	case !unicode.IsLower(first):
+		r.q = append(r.q, r.newSyntaxError(fmt.Sprintf("configuration key %q must begin with a lower-case letter", key)))
+	case bytes.IndexFunc(key, unicode.IsSpace) >= 0:
+		r.q = append(r.q, r.newSyntaxError(fmt.Sprintf("configuration key %q must not contain spaces", key)))
+	case bytes.IndexFunc(key, unicode.IsUpper) >= 0:
+		r.q = append(r.q, r.newSyntaxError(fmt.Sprintf("configuration key %q must not contain upper-case letters", key)))
+	default:
+		// The key is fine, so the value must not be separated
+		// by a space.
+		r.q = append(r.q, r.newSyntaxError(fmt.Sprintf("configuration key %q must be followed by a space", key)))
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchfmt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	const input = `goos: linux
+Goarch: amd64
+my key: value
+myKey: value
+key:value
+see https://golang.org
+--- FAIL: TestFoo
+BenchmarkOK 100 1 ns/op
+BenchmarkZero 0 1 ns/op
+BenchmarkNeg -1 1 ns/op
+BenchmarkNum 100 1 ns/op 2 3
+BenchmarkDup 100 1 ns/op 2 sec/op 3 B/op
+BenchmarkBad 100
+Unit ns/op better=lower assume=exact
+Unit B/op better=more
+Unit B/op assume=normal
+Unit B/op color=red
+note: a
+BenchmarkOK 100 1 ns/op
+goos:
+BenchmarkOK 100 1 ns/op
+BenchmarkUnits 100 1 NS/op 2 ms/op 3 KiB/op 4 allocs 5 Widgets/op 6 MB/s
+BenchmarkCase 100 1 widgets/op
+`
+	want := []string{
+		"test:2: configuration key \"Goarch\" must begin with a lower-case letter",
+		"test:3: configuration key \"my key\" must not contain spaces",
+		"test:4: configuration key \"myKey\" must not contain upper-case letters",
+		"test:5: configuration key \"key\" must be followed by a space",
+		"test:9: iteration count must be positive",
+		"test:10: iteration count must be positive",
+		"test:11: unit \"3\" looks like a number",
+		"test:12: duplicate measurements in unit sec/op",
+		"test:13: missing measurements",
+		"test:15: unit metadata better=more must be higher or lower",
+		"test:16: unit metadata assume=normal must be nothing or exact",
+		"test:17: unknown unit metadata key \"color\"",
+		"test:19: file configuration keys of BenchmarkOK differ from run on line 8: have [goos note], had [goos]",
+		"test:21: file configuration keys of BenchmarkOK differ from run on line 8: have [note], had [goos]",
+		"test:22: unit \"NS/op\" should be spelled \"ns/op\"",
+		"test:22: unit \"ms/op\" is scaled but won't be tidied; use ns or sec",
+		"test:22: unit \"KiB/op\" is scaled but won't be tidied; use B or MB",
+		"test:22: unit \"allocs\" has no denominator, such as /op",
+		"test:23: unit \"widgets/op\" differs only in case from \"Widgets/op\" on line 22",
+	}
+
+	errs, err := Validate(strings.NewReader(input), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range errs {
+		fileName, line := e.Pos()
+		got = append(got, fmt.Sprintf("%s:%d: %s", fileName, line, e.Msg))
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -8,28 +8,94 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"sort"
 )
 
 // A Writer writes the Go benchmark format.
 type Writer struct {
-	w   io.Writer
-	buf bytes.Buffer
+	w    io.Writer
+	buf  bytes.Buffer
+	opts WriterOptions
 
 	first      bool
 	fileConfig map[string]Config
 	order      []string
+	lines      []configLine
+	aligned    []alignedLine // with Align, results not yet written
+}
+
+// WriterOptions control the formatting of a Writer's output. The zero
+// value of WriterOptions reproduces the input as closely as possible.
+//
+// The Canonical options are useful for producing output that is
+// stable across tools and easy to diff, such as golden files.
+type WriterOptions struct {
+	// SortConfig sorts the lines in each block of file configuration
+	// by key. Otherwise, keys are written in the order they first
+	// appeared.
+	SortConfig bool
+
+	// Align writes benchmark lines as tab-separated columns with
+	// names padded to the longest name in their configuration block
+	// and right-aligned iteration counts and values, similar to the
+	// output of "go test -bench". Otherwise, fields are separated by
+	// a single space. Since the names of a block are only known at
+	// its end, its results are held back until then, so Flush must
+	// be called after the last Write.
+	Align bool
+
+	// Tidy writes tidied values and units (see benchunit.Tidy)
+	// rather than the original values and units. This also applies
+	// to unit metadata.
+	Tidy bool
+
+	// IgnoreInternalConfig ignores internal (non-File)
+	// configuration entirely. Internal configuration is never
+	// written, but by default changes to it start a new
+	// configuration block so the output retains the boundaries
+	// between results with different configurations.
+	IgnoreInternalConfig bool
+}
+
+// Canonical is a WriterOptions that enables all canonicalizing options.
+var Canonical = WriterOptions{
+	SortConfig:           true,
+	Align:                true,
+	Tidy:                 true,
+	IgnoreInternalConfig: true,
+}
+
+// alignedLine is a benchmark line held back by Align until the names
+// of its configuration block are known.
+type alignedLine struct {
+	name   string // including the "Benchmark" prefix
+	fields string // iterations and values
+}
+
+// configLine is a single line of a file configuration block. If
+// value is nil, the line deletes key.
+type configLine struct {
+	key   string
+	value []byte
 }
 
 // NewWriter returns a writer that writes Go benchmark results to w.
 func NewWriter(w io.Writer) *Writer {
-	return &Writer{w: w, first: true, fileConfig: make(map[string]Config)}
+	return NewWriterOptions(w, WriterOptions{})
+}
+
+// NewWriterOptions returns a writer that writes Go benchmark results
+// to w, formatted according to opts.
+func NewWriterOptions(w io.Writer, opts WriterOptions) *Writer {
+	return &Writer{w: w, opts: opts, first: true, fileConfig: make(map[string]Config)}
 }
 
 // Write writes Record rec to w. If rec is a *Result and rec's file
 // configuration differs from the current file configuration in w, it
 // first emits the appropriate file configuration lines. For
 // Result.Values that have a non-zero OrigUnit, this uses OrigValue and
-// OrigUnit in order to better reproduce the original input.
+// OrigUnit in order to better reproduce the original input, unless the
+// Writer's options specify Tidy.
 func (w *Writer) Write(rec Record) error {
 	switch rec := rec.(type) {
 	case *Result:
@@ -43,40 +109,95 @@ func (w *Writer) Write(rec Record) error {
 		return fmt.Errorf("unknown Record type %T", rec)
 	}
 
-	// Flush the buffer out to the io.Writer. Write to the buffer
-	// can't fail, so we only have to check if this fails.
+	return w.flushBuf()
+}
+
+// Flush writes any results held back by the Align option. It must be
+// called after the last Write if Align is set.
+func (w *Writer) Flush() error {
+	w.writeAligned()
+	return w.flushBuf()
+}
+
+// flushBuf flushes the buffer out to the io.Writer. Write to the
+// buffer can't fail, so we only have to check if this fails.
+func (w *Writer) flushBuf() error {
 	_, err := w.w.Write(w.buf.Bytes())
 	w.buf.Reset()
 	return err
 }
 
+// writeAligned writes the results held back by the Align option,
+// padding their names to the same width.
+func (w *Writer) writeAligned() {
+	width := 0
+	for _, l := range w.aligned {
+		width = max(width, len(l.name))
+	}
+	for _, l := range w.aligned {
+		fmt.Fprintf(&w.buf, "%-*s\t%s\n", width, l.name, l.fields)
+	}
+	w.aligned = w.aligned[:0]
+}
+
 func (w *Writer) writeResult(res *Result) {
 	// If any file config changed, write out the changes.
-	if len(w.fileConfig) != len(res.Config) {
+	if w.configChanged(res) {
+		w.writeAligned()
 		w.writeFileConfig(res)
-	} else {
-		for _, cfg := range res.Config {
-			if have, ok := w.fileConfig[cfg.Key]; !ok || !bytes.Equal(cfg.Value, have.Value) || cfg.File != have.File {
-				w.writeFileConfig(res)
-				break
-			}
-		}
 	}
 
 	// Print the benchmark line.
-	fmt.Fprintf(&w.buf, "Benchmark%s %d", res.Name, res.Iters)
-	for _, val := range res.Values {
-		if val.OrigUnit == "" {
-			fmt.Fprintf(&w.buf, " %v %s", val.Value, val.Unit)
-		} else {
-			fmt.Fprintf(&w.buf, " %v %s", val.OrigValue, val.OrigUnit)
+	if w.opts.Align {
+		var fields bytes.Buffer
+		fmt.Fprintf(&fields, "%8d", res.Iters)
+		for _, val := range res.Values {
+			value, unit := w.value(val)
+			fmt.Fprintf(&fields, "\t%10v %s", value, unit)
 		}
+		w.aligned = append(w.aligned, alignedLine{"Benchmark" + res.Name.String(), fields.String()})
+	} else {
+		fmt.Fprintf(&w.buf, "Benchmark%s %d", res.Name, res.Iters)
+		for _, val := range res.Values {
+			value, unit := w.value(val)
+			fmt.Fprintf(&w.buf, " %v %s", value, unit)
+		}
+		w.buf.WriteByte('\n')
 	}
-	w.buf.WriteByte('\n')
 
 	w.first = false
 }
 
+// value returns the value and unit of val to write.
+func (w *Writer) value(val Value) (float64, string) {
+	if val.OrigUnit != "" && !w.opts.Tidy {
+		return val.OrigValue, val.OrigUnit
+	}
+	return val.Value, val.Unit
+}
+
+// includeConfig reports whether cfg is tracked by w.
+func (w *Writer) includeConfig(cfg *Config) bool {
+	return cfg.File || !w.opts.IgnoreInternalConfig
+}
+
+// configChanged reports whether the tracked configuration of res
+// differs from the current configuration of w.
+func (w *Writer) configChanged(res *Result) bool {
+	n := 0
+	for i := range res.Config {
+		cfg := &res.Config[i]
+		if !w.includeConfig(cfg) {
+			continue
+		}
+		n++
+		if have, ok := w.fileConfig[cfg.Key]; !ok || !bytes.Equal(cfg.Value, have.Value) || cfg.File != have.File {
+			return true
+		}
+	}
+	return n != len(w.fileConfig)
+}
+
 func (w *Writer) writeFileConfig(res *Result) {
 	if !w.first {
 		// Configuration blocks after results get an extra blank.
@@ -85,13 +206,17 @@ func (w *Writer) writeFileConfig(res *Result) {
 	}
 
 	// Walk keys we know to find changes and deletions.
+	w.lines = w.lines[:0]
 	for i := 0; i < len(w.order); i++ {
 		key := w.order[i]
 		have := w.fileConfig[key]
 		idx, ok := res.ConfigIndex(key)
+		if ok && !w.includeConfig(&res.Config[idx]) {
+			ok = false
+		}
 		if !ok {
 			// Key was deleted.
-			fmt.Fprintf(&w.buf, "%s:\n", key)
+			w.lines = append(w.lines, configLine{key, nil})
 			delete(w.fileConfig, key)
 			copy(w.order[i:], w.order[i+1:])
 			w.order = w.order[:len(w.order)-1]
@@ -106,7 +231,7 @@ func (w *Writer) writeFileConfig(res *Result) {
 		// Value changed.
 		if cfg.File {
 			// Omit internal config.
-			fmt.Fprintf(&w.buf, "%s: %s\n", key, cfg.Value)
+			w.lines = append(w.lines, configLine{key, cfg.Value})
 		}
 		have.Value = append(have.Value[:0], cfg.Value...)
 		have.File = cfg.File
@@ -114,17 +239,29 @@ func (w *Writer) writeFileConfig(res *Result) {
 	}
 
 	// Find new keys.
-	if len(w.fileConfig) != len(res.Config) {
-		for _, cfg := range res.Config {
-			if _, ok := w.fileConfig[cfg.Key]; ok {
-				continue
-			}
-			// New key.
-			if cfg.File {
-				fmt.Fprintf(&w.buf, "%s: %s\n", cfg.Key, cfg.Value)
-			}
-			w.fileConfig[cfg.Key] = Config{cfg.Key, append([]byte(nil), cfg.Value...), cfg.File}
-			w.order = append(w.order, cfg.Key)
+	for i := range res.Config {
+		cfg := &res.Config[i]
+		if _, ok := w.fileConfig[cfg.Key]; ok || !w.includeConfig(cfg) {
+			continue
+		}
+		// New key.
+		if cfg.File {
+			w.lines = append(w.lines, configLine{cfg.Key, cfg.Value})
+		}
+		w.fileConfig[cfg.Key] = Config{cfg.Key, append([]byte(nil), cfg.Value...), cfg.File}
+		w.order = append(w.order, cfg.Key)
+	}
+
+	if w.opts.SortConfig {
+		sort.SliceStable(w.lines, func(i, j int) bool {
+			return w.lines[i].key < w.lines[j].key
+		})
+	}
+	for _, line := range w.lines {
+		if line.value == nil {
+			fmt.Fprintf(&w.buf, "%s:\n", line.key)
+		} else {
+			fmt.Fprintf(&w.buf, "%s: %s\n", line.key, line.value)
 		}
 	}
 
@@ -132,5 +269,10 @@ func (w *Writer) writeFileConfig(res *Result) {
 }
 
 func (w *Writer) writeUnitMetadata(m *UnitMetadata) {
-	fmt.Fprintf(&w.buf, "Unit %s %s=%s\n", m.OrigUnit, m.Key, m.Value)
+	w.writeAligned()
+	unit := m.OrigUnit
+	if w.opts.Tidy {
+		unit = m.Unit
+	}
+	fmt.Fprintf(&w.buf, "Unit %s %s=%s\n", unit, m.Key, m.Value)
 }
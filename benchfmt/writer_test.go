@@ -49,3 +49,123 @@ BenchmarkTwo 1 1 no-tidy-B/op
 		t.Fatalf("want:\n%sgot:\n%s", input, out.String())
 	}
 }
+
+func TestWriterOptions(t *testing.T) {
+	const input = `Unit ns/op a=1
+b: 1
+a: 1
+
+BenchmarkOne 100 1 ns/op 2 B/op
+
+b: 2
+a:
+c: 1
+
+BenchmarkOne 100 1 ns/op 2 B/op
+`
+
+	check := func(t *testing.T, input string, opts WriterOptions, initConfig []string, want string) {
+		t.Helper()
+		out := new(strings.Builder)
+		w := NewWriterOptions(out, opts)
+		r := new(Reader)
+		r.Reset(strings.NewReader(input), "test", initConfig...)
+		for r.Scan() {
+			if err := w.Write(r.Result()); err != nil {
+				t.Fatal(err)
+			}
+			// Change internal config between results.
+			if res, ok := r.Result().(*Result); ok {
+				res.SetConfig(".x", res.GetConfig(".x")+"x")
+			}
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != want {
+			t.Errorf("want:\n%sgot:\n%s", want, out.String())
+		}
+	}
+
+	t.Run("sort", func(t *testing.T) {
+		check(t, input, WriterOptions{SortConfig: true}, nil, `Unit ns/op a=1
+a: 1
+b: 1
+
+BenchmarkOne 100 1 ns/op 2 B/op
+
+a:
+b: 2
+c: 1
+
+BenchmarkOne 100 1 ns/op 2 B/op
+`)
+	})
+	t.Run("align", func(t *testing.T) {
+		check(t, input, WriterOptions{Align: true}, nil, `Unit ns/op a=1
+b: 1
+a: 1
+
+BenchmarkOne	     100	         1 ns/op	         2 B/op
+
+b: 2
+a:
+c: 1
+
+BenchmarkOne	     100	         1 ns/op	         2 B/op
+`)
+
+		// Names are padded to the longest in their block.
+		const names = `a: 1
+
+BenchmarkOne 100 1 ns/op
+BenchmarkThree/sub 10 20 ns/op
+BenchmarkTwo 1 300 ns/op
+
+a: 2
+
+BenchmarkFour 1 1 ns/op
+`
+		check(t, names, WriterOptions{Align: true, IgnoreInternalConfig: true}, nil, `a: 1
+
+BenchmarkOne      	     100	         1 ns/op
+BenchmarkThree/sub	      10	        20 ns/op
+BenchmarkTwo      	       1	       300 ns/op
+
+a: 2
+
+BenchmarkFour	       1	         1 ns/op
+`)
+	})
+	t.Run("tidy", func(t *testing.T) {
+		check(t, input, WriterOptions{Tidy: true}, nil, `Unit sec/op a=1
+b: 1
+a: 1
+
+BenchmarkOne 100 1e-09 sec/op 2 B/op
+
+b: 2
+a:
+c: 1
+
+BenchmarkOne 100 1e-09 sec/op 2 B/op
+`)
+	})
+	t.Run("internal", func(t *testing.T) {
+		const input = `a: 1
+
+BenchmarkOne 100 1 ns/op
+BenchmarkOne 100 1 ns/op
+`
+		// By default, internal config changes start a new block.
+		check(t, input, WriterOptions{}, []string{".x", "x"}, `a: 1
+
+BenchmarkOne 100 1 ns/op
+
+
+BenchmarkOne 100 1 ns/op
+`)
+		// But they can be ignored.
+		check(t, input, WriterOptions{IgnoreInternalConfig: true}, []string{".x", "x"}, input)
+	})
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// A CommitHistory provides the order of commits in a version control
+// history.
+type CommitHistory interface {
+	// Position returns the position of commit in the history.
+	// Commits that come earlier in the history have smaller
+	// positions. commit may be abbreviated. If commit isn't in the
+	// history, or an abbreviated commit is ambiguous, Position
+	// returns false.
+	Position(commit string) (pos int, ok bool)
+}
+
+// CommitOrder returns a comparison function that orders commits by
+// their position in h. Commits that aren't in h sort after those that
+// are. The result can be passed to ProjectionParser.RegisterOrder.
+func CommitOrder(h CommitHistory) func(a, b string) int {
+	return func(a, b string) int {
+		aa, oka := h.Position(a)
+		bb, okb := h.Position(b)
+		switch {
+		case oka && okb:
+			return aa - bb
+		case oka:
+			return -1
+		case okb:
+			return 1
+		}
+		// The values are unordered.
+		return 0
+	}
+}
+
+// A gitHistory is a CommitHistory read from a git repository.
+type gitHistory struct {
+	pos    map[string]int
+	hashes []string // Sorted, for abbreviated lookups
+}
+
+// NewGitHistory returns the history of the git repository in dir,
+// which may be any directory in the repository's work tree. This
+// includes the history of all branches, in topological order, so a
+// commit's position is always after the positions of its parents.
+//
+// NewGitHistory runs the git command to read the repository's
+// history.
+func NewGitHistory(dir string) (CommitHistory, error) {
+	cmd := exec.Command("git", "rev-list", "--all", "--topo-order", "--reverse")
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("reading git history of %s: %s", dir, msg)
+		}
+		return nil, fmt.Errorf("reading git history of %s: %w", dir, err)
+	}
+
+	h := &gitHistory{pos: make(map[string]int)}
+	for i, hash := range strings.Fields(string(out)) {
+		h.pos[hash] = i
+		h.hashes = append(h.hashes, hash)
+	}
+	sort.Strings(h.hashes)
+	return h, nil
+}
+
+func (h *gitHistory) Position(commit string) (int, bool) {
+	commit = strings.ToLower(commit)
+	if pos, ok := h.pos[commit]; ok {
+		return pos, true
+	}
+	// Look for a unique hash with this prefix. Very short prefixes
+	// are more likely to be something other than a hash.
+	if len(commit) < 4 {
+		return 0, false
+	}
+	i := sort.SearchStrings(h.hashes, commit)
+	if i == len(h.hashes) || !strings.HasPrefix(h.hashes[i], commit) {
+		return 0, false
+	}
+	if i+1 < len(h.hashes) && strings.HasPrefix(h.hashes[i+1], commit) {
+		// Ambiguous.
+		return 0, false
+	}
+	return h.pos[h.hashes[i]], true
+}
@@ -0,0 +1,132 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+type sliceHistory []string
+
+func (h sliceHistory) Position(commit string) (int, bool) {
+	for i, c := range h {
+		if c == commit {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func TestCommitOrder(t *testing.T) {
+	var pp ProjectionParser
+	pp.RegisterOrder("commit", CommitOrder(sliceHistory{"c1", "c2", "c3"}))
+	s, err := pp.Parse("hash@commit", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := []Key{
+		p(t, s, "", "hash", "c3"),
+		p(t, s, "", "hash", "zz"),
+		p(t, s, "", "hash", "c1"),
+		p(t, s, "", "hash", "c2"),
+	}
+	SortKeys(k)
+	var got []string
+	for _, key := range k {
+		got = append(got, key.String())
+	}
+	if want := "hash:c1 hash:c2 hash:c3 hash:zz"; strings.Join(got, " ") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+}
+
+func TestGitHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	// Create a repository with a merge:
+	//
+	//	c1 - c2 - c4
+	//	   \     /
+	//	    c3 -
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "init.defaultBranch=main"}, args...)...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	commit := func(msg string) string {
+		t.Helper()
+		git("commit", "-q", "--allow-empty", "-m", msg)
+		return git("rev-parse", "HEAD")
+	}
+	git("init", "-q")
+	c1 := commit("c1")
+	git("checkout", "-q", "-b", "side")
+	c3 := commit("c3")
+	git("checkout", "-q", "main")
+	c2 := commit("c2")
+	git("merge", "-q", "--no-ff", "-m", "c4", "side")
+	c4 := git("rev-parse", "HEAD")
+
+	h, err := NewGitHistory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pos := func(c string) int {
+		t.Helper()
+		p, ok := h.Position(c)
+		if !ok {
+			t.Fatalf("commit %s not found", c)
+		}
+		return p
+	}
+	if !(pos(c1) < pos(c2) && pos(c1) < pos(c3) && pos(c2) < pos(c4) && pos(c3) < pos(c4)) {
+		t.Errorf("positions not in topological order: c1=%d c2=%d c3=%d c4=%d", pos(c1), pos(c2), pos(c3), pos(c4))
+	}
+	if pos(c4[:10]) != pos(c4) || pos(strings.ToUpper(c4[:10])) != pos(c4) {
+		t.Errorf("abbreviated hash has a different position")
+	}
+	if _, ok := h.Position("0123456789"); ok {
+		t.Errorf("unknown commit found")
+	}
+
+	// Use it via a projection.
+	s, _ := mustParse(t, "commit@git("+dir+")")
+	k := []Key{
+		p(t, s, "", "commit", c4[:12]),
+		p(t, s, "", "commit", c1),
+		p(t, s, "", "commit", "unknown"),
+		p(t, s, "", "commit", c3[:8]),
+	}
+	SortKeys(k)
+	var got []string
+	for _, key := range k {
+		got = append(got, key.String())
+	}
+	want := []string{"commit:" + c1, "commit:" + c3[:8], "commit:" + c4[:12], "commit:unknown"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Errors.
+	f, _ := NewFilter("*")
+	if _, err := (&ProjectionParser{}).Parse("commit@git("+t.TempDir()+")", f); err == nil {
+		t.Errorf("want error for non-repository")
+	}
+	if _, err := (&ProjectionParser{}).Parse("commit@num(x)", f); err == nil || !strings.Contains(err.Error(), `order "num" does not take arguments`) {
+		t.Errorf("want error for arguments to num, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+// A Dependency records that, across a set of observations, the value
+// of one field determines the value of another field.
+type Dependency struct {
+	// From and To are the two fields. Any two observations with
+	// the same value of From also have the same value of To.
+	From, To *Field
+
+	// Mutual indicates that To also determines From, so the values
+	// of From and To correspond one-to-one.
+	Mutual bool
+}
+
+// FunctionalDependencies returns the dependencies between the fields
+// of a set of observations. Each observation is a tuple of Keys, such
+// as the row and column Key of a single table cell. All observations
+// must have the same number of Keys, and all Keys at the same index in
+// each observation must have the same Projection. Dependencies may be
+// between fields of the same or different Projections.
+//
+// Fields that take fewer than two distinct values are trivially
+// determined by every other field, so these are omitted. Mutual
+// dependencies are returned once, with From being the field that
+// appears first. Dependencies are returned in order of From, then To,
+// with fields ordered by Key index and then by flattened field order.
+//
+// This is useful for explaining very sparse tables. For example, if
+// each row of a table has results in only one column, there's likely
+// a field in the column Projection that's determined by a field in
+// the row Projection.
+func FunctionalDependencies(obs [][]Key) []Dependency {
+	if len(obs) <= 1 {
+		return nil
+	}
+
+	// Collect the fields and assign each distinct value of each
+	// field a small integer ID.
+	type depField struct {
+		f    *Field
+		ids  []int // Value ID in each observation
+		nIDs int
+	}
+	var fields []*depField
+	for j := range obs[0] {
+		col := make([]Key, len(obs))
+		for i, o := range obs {
+			if len(o) != len(obs[0]) {
+				panic("observations must all have the same number of Keys")
+			}
+			col[i] = o[j]
+		}
+		proj := commonProjection(col)
+		if proj == nil {
+			continue
+		}
+		for _, f := range proj.FlattenedFields() {
+			df := &depField{f: f, ids: make([]int, len(obs))}
+			valIDs := make(map[string]int)
+			for i, k := range col {
+				var val string
+				if !k.IsZero() {
+					val = k.Get(f)
+				}
+				id, ok := valIDs[val]
+				if !ok {
+					id = len(valIDs)
+					valIDs[val] = id
+				}
+				df.ids[i] = id
+			}
+			df.nIDs = len(valIDs)
+			if df.nIDs >= 2 {
+				fields = append(fields, df)
+			}
+		}
+	}
+
+	// determines reports whether a's value determines b's value.
+	determines := func(a, b *depField) bool {
+		m := make([]int, a.nIDs)
+		for i := range m {
+			m[i] = -1
+		}
+		for i, aID := range a.ids {
+			bID := b.ids[i]
+			if m[aID] == -1 {
+				m[aID] = bID
+			} else if m[aID] != bID {
+				return false
+			}
+		}
+		return true
+	}
+
+	var out []Dependency
+	for i, a := range fields {
+		for j, b := range fields {
+			if i == j || !determines(a, b) {
+				continue
+			}
+			mutual := a.nIDs == b.nIDs && determines(b, a)
+			if mutual && j < i {
+				// Already reported as b -> a.
+				continue
+			}
+			out = append(out, Dependency{a.f, b.f, mutual})
+		}
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestFunctionalDependencies(t *testing.T) {
+	rows, _ := mustParse(t, ".name,/size")
+	cols, _ := mustParse(t, "pkg,goos")
+
+	check := func(obs [][]Key, want ...string) {
+		t.Helper()
+		var got []string
+		for _, d := range FunctionalDependencies(obs) {
+			arrow := "->"
+			if d.Mutual {
+				arrow = "<->"
+			}
+			got = append(got, fmt.Sprintf("%s%s%s", d.From.Name, arrow, d.To.Name))
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	}
+	obs := func(name, size, pkg, goos string) []Key {
+		return []Key{
+			p(t, rows, name+"/size="+size),
+			p(t, cols, "", "pkg", pkg, "goos", goos),
+		}
+	}
+
+	check(nil)
+	check([][]Key{obs("A", "1", "p", "linux")})
+
+	// .name determines pkg, and goos is constant.
+	check([][]Key{
+		obs("A", "1", "p", "linux"),
+		obs("A", "2", "p", "linux"),
+		obs("B", "1", "q", "linux"),
+		obs("B", "2", "q", "linux"),
+	}, ".name<->pkg")
+
+	// .name determines pkg, but not vice-versa.
+	check([][]Key{
+		obs("A", "1", "p", "linux"),
+		obs("B", "2", "p", "linux"),
+		obs("C", "1", "q", "linux"),
+	}, ".name->/size", ".name->pkg")
+
+	// Fully crossed fields have no dependencies.
+	check([][]Key{
+		obs("A", "1", "p", "linux"),
+		obs("A", "1", "p", "darwin"),
+		obs("A", "2", "p", "linux"),
+		obs("A", "2", "p", "darwin"),
+	})
+}
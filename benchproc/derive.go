@@ -0,0 +1,247 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"golang.org/x/perf/benchfmt"
+	"golang.org/x/perf/benchproc/internal/parse"
+	"golang.org/x/perf/benchunit"
+)
+
+// A Derivation computes a derived measurement from the measurements
+// and keys of a benchfmt.Result, such as time per element processed
+// or bytes per allocation.
+type Derivation struct {
+	def string // The definition, for WithNameSchema
+
+	// unit is the derived unit as written by the user, and
+	// tidyUnit and factor give its tidied form (see benchunit.Tidy).
+	unit     string
+	tidyUnit string
+	factor   float64
+
+	eval derivFn
+}
+
+// derivFn evaluates a derivation expression over res. It returns
+// false if any operand is missing from res.
+type derivFn func(res *benchfmt.Result) (float64, bool)
+
+// NewDerivation constructs a Derivation from a derived unit
+// definition, such as "ns/elem=sec/op / /size". See "go doc
+// golang.org/x/perf/benchproc/syntax" for a description of derived
+// unit syntax.
+func NewDerivation(def string) (*Derivation, error) {
+	return newDerivation(def, nil)
+}
+
+// newDerivation constructs a Derivation whose definition may use the
+// sub-name keys defined by schema, which may be nil.
+func newDerivation(def string, schema *NameSchema) (*Derivation, error) {
+	d, err := parse.ParseDerivation(def)
+	if err != nil {
+		return nil, err
+	}
+
+	var walk func(e parse.Expr) (derivFn, error)
+	walk = func(e parse.Expr) (derivFn, error) {
+		switch e := e.(type) {
+		case *parse.ExprOp:
+			l, err := walk(e.L)
+			if err != nil {
+				return nil, err
+			}
+			r, err := walk(e.R)
+			if err != nil {
+				return nil, err
+			}
+			return derivOp(e.Op, l, r), nil
+
+		case *parse.ExprOperand:
+			switch e.Kind {
+			case 'n':
+				v := e.Num
+				return func(res *benchfmt.Result) (float64, bool) {
+					return v, true
+				}, nil
+
+			case 'k':
+				if e.Tok == ".config" || e.Tok == ".unit" || e.Tok == ".value" {
+					return nil, &parse.SyntaxError{Query: def, Off: e.Off, Msg: e.Tok + " is not allowed in derived units"}
+				}
+				ext, err := newExtractor(e.Tok, schema)
+				if err != nil {
+					return nil, &parse.SyntaxError{Query: def, Off: e.Off, Msg: err.Error()}
+				}
+				return func(res *benchfmt.Result) (float64, bool) {
+					v, err := parseExactNum(string(ext(res)))
+					return v, err == nil
+				}, nil
+
+			case 'u':
+				unit := e.Tok
+				tidy, _ := tidyOperand(unit)
+				return func(res *benchfmt.Result) (float64, bool) {
+					// Match any unit that tidies to the same
+					// unit, and use the tidied value.
+					for _, val := range res.Values {
+						if u, factor := tidyOperand(val.Unit); u == tidy {
+							return val.Value * factor, true
+						}
+					}
+					// Fall back to a file configuration key.
+					v, err := parseExactNum(string(extractConfig(res, unit)))
+					return v, err == nil
+				}, nil
+			}
+		}
+		panic(fmt.Sprintf("unknown expression node %T", e))
+	}
+	eval, err := walk(d.Expr)
+	if err != nil {
+		return nil, err
+	}
+
+	tidyFactor, tidyUnit := benchunit.Tidy(1, d.Unit)
+	return &Derivation{def, d.Unit, tidyUnit, tidyFactor, eval}, nil
+}
+
+// tidyOperands caches the results of tidyOperand.
+var tidyOperands sync.Map // unit string -> tidyOperandEntry
+
+type tidyOperandEntry struct {
+	unit   string
+	factor float64
+}
+
+// tidyOperand returns the tidied form of a unit operand and the
+// factor that converts values to it. Beyond benchunit.Tidy, it
+// understands the time and byte units of .value literals, so "ms/op"
+// tidies to "sec/op" and "KiB" to "B".
+func tidyOperand(unit string) (string, float64) {
+	if e, ok := tidyOperands.Load(unit); ok {
+		e := e.(tidyOperandEntry)
+		return e.unit, e.factor
+	}
+	e := tidyOperandEntry{unit, 1}
+	if f, u, err := parseValueLit("1" + unit); err == nil && u != "" {
+		e = tidyOperandEntry{u, f}
+	}
+	tidyOperands.Store(unit, e)
+	return e.unit, e.factor
+}
+
+func derivOp(op byte, l, r derivFn) derivFn {
+	return func(res *benchfmt.Result) (float64, bool) {
+		a, ok := l(res)
+		if !ok {
+			return 0, false
+		}
+		b, ok := r(res)
+		if !ok {
+			return 0, false
+		}
+		switch op {
+		case '+':
+			return a + b, true
+		case '-':
+			return a - b, true
+		case '*':
+			return a * b, true
+		case '/':
+			return a / b, true
+		}
+		panic(fmt.Sprintf("unknown operator %q", op))
+	}
+}
+
+// Unit returns the derived unit, as it will appear in
+// benchfmt.Value.Unit. This is always a tidied unit.
+func (d *Derivation) Unit() string {
+	return d.tidyUnit
+}
+
+// Apply computes the derived measurement for res and adds it to
+// res.Values, replacing any existing measurement with the same unit.
+// It reports whether the measurement could be computed. It can't be
+// computed if res is missing any unit or key referenced by the
+// derivation, if a key's value isn't a number, or if the result isn't
+// finite (for example, because of division by zero).
+//
+// Expressions are evaluated on tidied values. For example, "ns/op",
+// "ms/op", and "sec/op" all refer to the time in seconds. The result is
+// then taken to be in the tidied form of the derived unit, so
+// "ns/elem=sec/op / /size" yields a value in "sec/elem" whose original
+// unit is "ns/elem".
+func (d *Derivation) Apply(res *benchfmt.Result) bool {
+	v, ok := d.eval(res)
+	if !ok || math.IsNaN(v) || math.IsInf(v, 0) {
+		return false
+	}
+
+	val := benchfmt.Value{Value: v, Unit: d.tidyUnit}
+	if d.tidyUnit != d.unit {
+		val.OrigValue, val.OrigUnit = v/d.factor, d.unit
+	}
+	for i := range res.Values {
+		if res.Values[i].Unit == d.tidyUnit {
+			res.Values[i] = val
+			return true
+		}
+	}
+	res.Values = append(res.Values, val)
+	return true
+}
+
+// Derivations is a list of derivations. It implements flag.Value, so
+// it can be used as a repeatable command-line flag, where each use of
+// the flag adds a derived unit definition.
+type Derivations []*Derivation
+
+// String returns the derived units in ds, separated by commas.
+func (ds *Derivations) String() string {
+	var units []string
+	for _, d := range *ds {
+		units = append(units, d.unit)
+	}
+	return strings.Join(units, ",")
+}
+
+// Set parses def with NewDerivation and adds the result to ds.
+func (ds *Derivations) Set(def string) error {
+	d, err := NewDerivation(def)
+	if err != nil {
+		return err
+	}
+	*ds = append(*ds, d)
+	return nil
+}
+
+// WithNameSchema returns ds reconstructed so the definitions may use
+// the sub-name keys defined by schema. This lets a command parse its
+// -derive flags before its name schema flag.
+func (ds Derivations) WithNameSchema(schema *NameSchema) (Derivations, error) {
+	out := make(Derivations, len(ds))
+	for i, d := range ds {
+		var err error
+		if out[i], err = schema.NewDerivation(d.def); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Apply applies each derivation in ds to res, in order, so later
+// derivations can refer to units derived by earlier ones.
+func (ds Derivations) Apply(res *benchfmt.Result) {
+	for _, d := range ds {
+		d.Apply(res)
+	}
+}
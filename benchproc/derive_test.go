@@ -0,0 +1,152 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"flag"
+	"io"
+	"math"
+	"testing"
+
+	"golang.org/x/perf/benchfmt"
+)
+
+func TestDerivation(t *testing.T) {
+	mk := func() *benchfmt.Result {
+		res := r(t, "Name/size=4k", "cores", "4")
+		res.Values = []benchfmt.Value{
+			{Value: 100e-9, Unit: "sec/op", OrigValue: 100, OrigUnit: "ns/op"},
+			{Value: 64, Unit: "B/op"},
+			{Value: 2, Unit: "allocs/op"},
+		}
+		return res
+	}
+
+	check := func(t *testing.T, def string, want benchfmt.Value) {
+		t.Helper()
+		d, err := NewDerivation(def)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := mk()
+		if !d.Apply(res) {
+			t.Fatalf("%s: Apply failed", def)
+		}
+		got, ok := res.Value(d.Unit())
+		if !ok {
+			t.Fatalf("%s: no value for unit %s", def, d.Unit())
+		}
+		got2 := res.Values[len(res.Values)-1]
+		if math.Abs(got-want.Value) > 1e-9*math.Abs(want.Value) || got2.Unit != want.Unit || got2.OrigUnit != want.OrigUnit || math.Abs(got2.OrigValue-want.OrigValue) > 1e-9*math.Abs(want.OrigValue) {
+			t.Errorf("%s: got %+v, want %+v", def, got2, want)
+		}
+	}
+	checkFail := func(t *testing.T, def string) {
+		t.Helper()
+		d, err := NewDerivation(def)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := mk()
+		if d.Apply(res) {
+			t.Errorf("%s: Apply succeeded, want failure", def)
+		}
+		if len(res.Values) != 3 {
+			t.Errorf("%s: Apply modified Values", def)
+		}
+	}
+
+	t.Run("basic", func(t *testing.T) {
+		check(t, "B/alloc=B/op / allocs/op", benchfmt.Value{Value: 32, Unit: "B/alloc"})
+		check(t, "x=B/op + allocs/op * 2", benchfmt.Value{Value: 68, Unit: "x"})
+		check(t, "x=(B/op + allocs/op) * 2", benchfmt.Value{Value: 132, Unit: "x"})
+		check(t, "x=B/op * cores", benchfmt.Value{Value: 256, Unit: "x"})
+		check(t, "x=.iters * 3", benchfmt.Value{Value: 3, Unit: "x"})
+	})
+
+	t.Run("tidy", func(t *testing.T) {
+		// Operands are tidied regardless of how they're named,
+		// and the result is tidied.
+		check(t, "ns/elem=sec/op / /size", benchfmt.Value{Value: 100e-9 / 4000, Unit: "sec/elem", OrigValue: 100.0 / 4000, OrigUnit: "ns/elem"})
+		check(t, "ns/elem=ns/op / /size", benchfmt.Value{Value: 100e-9 / 4000, Unit: "sec/elem", OrigValue: 100.0 / 4000, OrigUnit: "ns/elem"})
+		check(t, "MB/s=/size / sec/op", benchfmt.Value{Value: 4000 / 100e-9, Unit: "B/s", OrigValue: 4000 / 100e-9 / 1e6, OrigUnit: "MB/s"})
+		check(t, "x=ms/op * 1e9", benchfmt.Value{Value: 100, Unit: "x"})
+		check(t, "x=KiB/op", benchfmt.Value{Value: 64, Unit: "x"})
+
+		// Measurements that aren't tidied match equivalent operands
+		// and are tidied, whichever way round.
+		for _, test := range []struct {
+			val benchfmt.Value
+			def string
+		}{
+			{benchfmt.Value{Value: 100, Unit: "ns/op"}, "x=sec/op * 1e9"},
+			{benchfmt.Value{Value: 100e-9, Unit: "sec/op"}, "x=ns/op * 1e9"},
+			{benchfmt.Value{Value: 1e-4, Unit: "ms/op"}, "x=ns/op * 1e9"},
+		} {
+			d, err := NewDerivation(test.def)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := &benchfmt.Result{Name: []byte("Name"), Iters: 1, Values: []benchfmt.Value{test.val}}
+			if !d.Apply(res) {
+				t.Errorf("%s on %+v: Apply failed", test.def, test.val)
+			} else if got, _ := res.Value("x"); math.Abs(got-100) > 1e-9 {
+				t.Errorf("%s on %+v: got %v, want 100", test.def, test.val, got)
+			}
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		checkFail(t, "x=foo/op * 2")
+		checkFail(t, "x=B/op * /missing")
+		checkFail(t, "x=B/op * .name")
+		checkFail(t, "x=B/op / 0")
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		d, err := NewDerivation("B/op=B/op * 2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := mk()
+		d.Apply(res)
+		if len(res.Values) != 3 || res.Values[1].Value != 128 {
+			t.Errorf("got %+v, want B/op replaced", res.Values)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, def := range []string{"x=.config * 2", "x=.value * 2", "x=", "x=/ 2"} {
+			if _, err := NewDerivation(def); err == nil {
+				t.Errorf("%s: want error", def)
+			}
+		}
+	})
+}
+
+func TestDerivations(t *testing.T) {
+	var ds Derivations
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&ds, "derive", "")
+	if err := fs.Parse([]string{"-derive", "B/alloc=B/op / allocs/op", "-derive", "bits/alloc=B/alloc * 8"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ds.String(); got != "B/alloc,bits/alloc" {
+		t.Errorf("got %q, want B/alloc,bits/alloc", got)
+	}
+
+	// Later derivations see earlier ones.
+	res := r(t, "Name")
+	res.Values = []benchfmt.Value{{Value: 64, Unit: "B/op"}, {Value: 2, Unit: "allocs/op"}}
+	ds.Apply(res)
+	if got, ok := res.Value("bits/alloc"); !ok || got != 256 {
+		t.Errorf("got bits/alloc %v, %v, want 256", got, ok)
+	}
+
+	if err := fs.Parse([]string{"-derive", "nope"}); err == nil {
+		t.Errorf("bad definition: want error")
+	}
+}
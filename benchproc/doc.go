@@ -25,7 +25,9 @@
 //
 // 2b. Optionally filter the benchfmt.Result according to a user-provided
 // predicate parsed by NewFilter. Filters can keep or discard entire
-// Results, or just particular measurements from a Result.
+// Results, or just particular measurements from a Result. To help
+// users debug filters, Filter.Explain reports which parts of a filter
+// matched a given Result.
 //
 // 2c. Project the benchfmt.Result using one or more Projections.
 // Projecting a Result extracts a subset of the information from a
@@ -47,4 +49,11 @@
 // 3. At the end of the Results stream, once all Results have been
 // grouped by their Keys, sort the Keys of each dimension using SortKeys
 // and present the data in the resulting order.
+//
+// Keys are only meaningful within their Projection, but a Projection's
+// layout can be saved with Projection.MarshalJSON and restored with
+// ProjectionParser.UnmarshalProjection, and Keys can be saved with
+// Key.MarshalText or Key.MarshalJSON and restored into the restored
+// Projection. This allows tools to cache grouped results between runs
+// or pass them to other processes.
 package benchproc
@@ -0,0 +1,171 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/perf/benchfmt"
+)
+
+// A FilterExplanation describes how each sub-expression of a Filter
+// matched a particular benchfmt.Result. It's intended to help users
+// understand why a filter dropped a result.
+type FilterExplanation struct {
+	// Expr is the filter expression of this node, such as
+	// ".name:Copy" or "(.name:Copy AND /size>1k)".
+	Expr string
+
+	// Match is the set of measurements matched by Expr.
+	Match Match
+
+	// Sub explains each operand of Expr if Expr is an AND, OR, or
+	// NOT expression.
+	Sub []*FilterExplanation
+
+	// units is the unit of each measurement of the Result.
+	units []string
+}
+
+// Explain evaluates f against res and returns a tree describing which
+// sub-expressions of f matched res. Each sub-expression is evaluated
+// independently, so operands of AND and OR expressions are explained
+// even if f would have short-circuited them.
+//
+// If f includes filters implied by projections, such as
+// "/size@(1k 2k)", these appear as operands of an AND at the root of
+// the tree.
+//
+// Explain does not modify res.
+func (f *Filter) Explain(res *benchfmt.Result) *FilterExplanation {
+	units := make([]string, len(res.Values))
+	for i, v := range res.Values {
+		units[i] = v.Unit
+	}
+	var walk func(n *filterNode) *FilterExplanation
+	walk = func(n *filterNode) *FilterExplanation {
+		m, x := n.fn(res)
+		e := &FilterExplanation{Expr: n.expr, Match: Match{len(res.Values), m, x}, units: units}
+		for _, sub := range n.subs {
+			e.Sub = append(e.Sub, walk(sub))
+		}
+		return e
+	}
+	return walk(f.tree)
+}
+
+// String formats e as an indented tree with one expression per line.
+// Each line begins with "[match]" or "[no match]", or, if the
+// expression matched only some measurements, which units matched and
+// which didn't, such as "[match sec/op; no match B/op]".
+func (e *FilterExplanation) String() string {
+	var buf strings.Builder
+	var walk func(e *FilterExplanation, indent string)
+	walk = func(e *FilterExplanation, indent string) {
+		buf.WriteString(indent)
+		buf.WriteString("[")
+		buf.WriteString(e.status())
+		buf.WriteString("] ")
+		buf.WriteString(e.Expr)
+		buf.WriteString("\n")
+		for _, sub := range e.Sub {
+			walk(sub, indent+"  ")
+		}
+	}
+	walk(e, "")
+	return buf.String()
+}
+
+// status returns a description of which measurements e matched.
+func (e *FilterExplanation) status() string {
+	if e.Match.All() {
+		return "match"
+	} else if !e.Match.Any() {
+		return "no match"
+	}
+	var yes, no []string
+	for i, unit := range e.units {
+		if e.Match.Test(i) {
+			yes = append(yes, unit)
+		} else {
+			no = append(no, unit)
+		}
+	}
+	return "match " + strings.Join(yes, ", ") + "; no match " + strings.Join(no, ", ")
+}
+
+// A ProjectionExplanation describes how the keys of the projections
+// parsed by a ProjectionParser were divided between specific fields and
+// the group fields ".config" and ".fullname".
+type ProjectionExplanation struct {
+	// Config is the file configuration keys that have been
+	// absorbed into a ".config" field so far, in sorted order.
+	// Since .config fields are created as Results are projected,
+	// this only includes keys observed in projected Results. This
+	// is nil if no projection includes .config.
+	Config []string
+
+	// ConfigExcluded is the file configuration keys that are
+	// excluded from ".config" because a projection names them
+	// specifically, in sorted order.
+	ConfigExcluded []string
+
+	// Fullname reports whether any projection includes
+	// ".fullname".
+	Fullname bool
+
+	// FullnameExcluded is the name keys, such as ".name" and
+	// "/size", that are excluded from ".fullname" because a
+	// projection names them specifically, in the order they were
+	// parsed.
+	FullnameExcluded []string
+}
+
+// Explain returns a description of how the keys of all projections
+// parsed by p so far are divided between specific fields and the
+// group fields ".config" and ".fullname".
+func (p *ProjectionParser) Explain() *ProjectionExplanation {
+	e := &ProjectionExplanation{Fullname: p.haveFullname}
+	seen := make(map[string]bool)
+	for _, group := range p.configGroups {
+		for _, f := range group.Sub {
+			if !seen[f.Name] {
+				seen[f.Name] = true
+				e.Config = append(e.Config, f.Name)
+			}
+		}
+	}
+	if e.Config == nil && len(p.configGroups) > 0 {
+		e.Config = []string{}
+	}
+	sort.Strings(e.Config)
+	for key := range p.configKeys {
+		e.ConfigExcluded = append(e.ConfigExcluded, key)
+	}
+	sort.Strings(e.ConfigExcluded)
+	e.FullnameExcluded = append(e.FullnameExcluded, p.fullnameKeys...)
+	return e
+}
+
+// String formats e as one line per group field. Lines for group
+// fields that don't appear in any projection are omitted.
+func (e *ProjectionExplanation) String() string {
+	var buf strings.Builder
+	list := func(keys []string) string {
+		if len(keys) == 0 {
+			return "(none)"
+		}
+		return strings.Join(keys, " ")
+	}
+	if e.Config != nil {
+		buf.WriteString(".config includes: " + list(e.Config) + "\n")
+		buf.WriteString(".config excludes: " + list(e.ConfigExcluded) + "\n")
+	}
+	if e.Fullname {
+		buf.WriteString(".fullname excludes: " + list(e.FullnameExcluded) + "\n")
+	}
+	return buf.String()
+}
@@ -0,0 +1,113 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"testing"
+
+	"golang.org/x/perf/benchfmt"
+)
+
+func TestFilterExplain(t *testing.T) {
+	check := func(t *testing.T, f *Filter, res *benchfmt.Result, want string) {
+		t.Helper()
+		if got := f.Explain(res).String(); got != want {
+			t.Errorf("got:\n%swant:\n%s", got, want)
+		}
+	}
+	res := r(t, "Copy/size=4k", "goos", "linux")
+	res.Values = []benchfmt.Value{{Value: 1, Unit: "sec/op"}, {Value: 2, Unit: "B/op"}, {Value: 3, Unit: "allocs/op"}}
+
+	t.Run("leaf", func(t *testing.T) {
+		f, err := NewFilter(".name:Copy")
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, f, res, "[match] .name:Copy\n")
+	})
+
+	t.Run("tree", func(t *testing.T) {
+		// The OR is explained in full even though the first
+		// operand short-circuits it.
+		f, err := NewFilter("(.name:Copy OR goos:darwin) -/size>1k")
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, f, res, `[no match] ((.name:Copy OR goos:darwin) AND -/size>1k)
+  [match] (.name:Copy OR goos:darwin)
+    [match] .name:Copy
+    [no match] goos:darwin
+  [no match] -/size>1k
+    [match] /size>1k
+`)
+	})
+
+	t.Run("units", func(t *testing.T) {
+		f, err := NewFilter(".name:Copy (.unit:sec/op OR .unit:B/op)")
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, f, res, `[match sec/op, B/op; no match allocs/op] (.name:Copy AND (.unit:sec/op OR .unit:B/op))
+  [match] .name:Copy
+  [match sec/op, B/op; no match allocs/op] (.unit:sec/op OR .unit:B/op)
+    [match sec/op; no match B/op, allocs/op] .unit:sec/op
+    [match B/op; no match sec/op, allocs/op] .unit:B/op
+`)
+		// Explain agrees with Match.
+		e := f.Explain(res)
+		m, _ := f.Match(res)
+		for i := range res.Values {
+			if e.Match.Test(i) != m.Test(i) {
+				t.Errorf("measurement %d: Explain says %v, Match says %v", i, e.Match.Test(i), m.Test(i))
+			}
+		}
+	})
+
+	t.Run("projection", func(t *testing.T) {
+		f, err := NewFilter("goos:linux")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var pp ProjectionParser
+		if _, err := pp.Parse("/size@(1k 2k)", f); err != nil {
+			t.Fatal(err)
+		}
+		check(t, f, res, `[no match] (/size@(1k 2k) AND goos:linux)
+  [no match] /size@(1k 2k)
+  [match] goos:linux
+`)
+	})
+}
+
+func TestProjectionExplain(t *testing.T) {
+	var pp ProjectionParser
+	f, _ := NewFilter("*")
+	cfg, err := pp.Parse(".config", f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pp.Parse(".fullname,goos,/size", f); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Project(r(t, "Copy/size=4k", "goos", "linux", "goarch", "amd64", "cpu", "Xeon"))
+
+	e := pp.Explain()
+	want := `.config includes: cpu goarch
+.config excludes: goos
+.fullname excludes: /size
+`
+	if got := e.String(); got != want {
+		t.Errorf("got:\n%swant:\n%s", got, want)
+	}
+
+	// Without .config or .fullname, there's nothing to explain.
+	var pp2 ProjectionParser
+	if _, err := pp2.Parse(".name,goos", f); err != nil {
+		t.Fatal(err)
+	}
+	if got := pp2.Explain().String(); got != "" {
+		t.Errorf("got %q, want empty", got)
+	}
+}
@@ -7,9 +7,11 @@ package benchproc
 import (
 	"bytes"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"golang.org/x/perf/benchfmt"
+	"golang.org/x/perf/benchproc/internal/parse"
 )
 
 // An extractor returns some field of a benchmark result. The
@@ -29,16 +31,19 @@ type extractor func(*benchfmt.Result) []byte
 // configuration).
 //
 // - "/{key}" for a benchmark sub-name key. This may be "/gomaxprocs"
-// and the extractor will normalize the name as needed.
+// and the extractor will normalize the name as needed. It may also be
+// a key defined by schema, which may be nil.
+//
+// - ".iters" for the iteration count.
 //
 // - Any other string is a file configuration key.
-func newExtractor(key string) (extractor, error) {
+func newExtractor(key string, schema *NameSchema) (extractor, error) {
 	if len(key) == 0 {
 		return nil, fmt.Errorf("key must not be empty")
 	}
 
 	switch {
-	case key == ".config", key == ".unit":
+	case key == ".config", key == ".unit", key == ".value":
 		// The caller should already have handled this more gracefully.
 		panic(key + " is not an extractor")
 
@@ -48,7 +53,13 @@ func newExtractor(key string) (extractor, error) {
 	case key == ".fullname":
 		return extractFull, nil
 
+	case key == ".iters":
+		return extractIters, nil
+
 	case strings.HasPrefix(key, "/"):
+		if ext := schema.extractor(key); ext != nil {
+			return ext, nil
+		}
 		// Construct the byte prefix to search for.
 		prefix := make([]byte, len(key)+1)
 		copy(prefix, key)
@@ -69,8 +80,9 @@ func newExtractor(key string) (extractor, error) {
 // configuration keys excluded. Any excluded sub-name keys will be
 // deleted from the name. If ".name" is excluded, the name will be
 // normalized to "*". This will ignore anything in the exclude list that
-// isn't in the form of a /-prefixed sub-name key or ".name".
-func newExtractorFullName(exclude []string) extractor {
+// isn't in the form of a /-prefixed sub-name key or ".name". Sub-name
+// keys defined by schema, which may be nil, are deleted, too.
+func newExtractorFullName(exclude []string, schema *NameSchema) extractor {
 	// Extract the sub-name keys, turn them into substrings and
 	// construct their normalized replacement.
 	//
@@ -100,7 +112,11 @@ func newExtractorFullName(exclude []string) extractor {
 		return extractFull
 	}
 	return func(res *benchfmt.Result) []byte {
-		return extractFullExcluded(res, delete, excName, excGomaxprocs)
+		name := res.Name
+		if n := schema.deleteKeys(name, exclude); n != nil {
+			name = n
+		}
+		return extractFullExcluded(name, delete, excName, excGomaxprocs)
 	}
 }
 
@@ -112,8 +128,12 @@ func extractFull(res *benchfmt.Result) []byte {
 	return res.Name.Full()
 }
 
-func extractFullExcluded(res *benchfmt.Result, delete [][]byte, excName, excGomaxprocs bool) []byte {
-	name := res.Name.Full()
+func extractIters(res *benchfmt.Result) []byte {
+	return strconv.AppendInt(nil, int64(res.Iters), 10)
+}
+
+func extractFullExcluded(full benchfmt.Name, delete [][]byte, excName, excGomaxprocs bool) []byte {
+	name := full.Full()
 	found := false
 	if excName {
 		found = true
@@ -135,7 +155,7 @@ func extractFullExcluded(res *benchfmt.Result, delete [][]byte, excName, excGoma
 	}
 
 	// Delete excluded keys from the name.
-	base, parts := res.Name.Parts()
+	base, parts := full.Parts()
 	var newName []byte
 	if excName {
 		newName = append(newName, '*')
@@ -185,3 +205,39 @@ func extractConfig(res *benchfmt.Result, key string) []byte {
 	}
 	return res.Config[pos].Value
 }
+
+// newRewriteExtractor returns an extractor that applies rewrite r to
+// the values extracted by ext.
+func newRewriteExtractor(ext extractor, r parse.Rewrite) extractor {
+	if r.Regexps != nil {
+		return func(res *benchfmt.Result) []byte {
+			val := ext(res)
+			for _, re := range r.Regexps {
+				m := re.FindSubmatchIndex(val)
+				if m == nil {
+					continue
+				}
+				if len(m) > 2 && m[2] >= 0 {
+					// Use the first capturing group.
+					return val[m[2]:m[3]]
+				}
+				return val[m[0]:m[1]]
+			}
+			return val
+		}
+	}
+
+	alias := make(map[string][]byte, len(r.Alias))
+	for _, a := range r.Alias {
+		if _, ok := alias[a.From]; !ok {
+			alias[a.From] = []byte(a.To)
+		}
+	}
+	return func(res *benchfmt.Result) []byte {
+		val := ext(res)
+		if to, ok := alias[string(val)]; ok {
+			return to
+		}
+		return val
+	}
+}
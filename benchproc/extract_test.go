@@ -22,7 +22,7 @@ func checkNameExtractor(t *testing.T, x extractor, fullName string, want string)
 func TestExtractName(t *testing.T) {
 	check := checkNameExtractor
 
-	x, err := newExtractor(".name")
+	x, err := newExtractor(".name", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -36,7 +36,7 @@ func TestExtractFullName(t *testing.T) {
 	check := checkNameExtractor
 
 	t.Run("basic", func(t *testing.T) {
-		x, err := newExtractor(".fullname")
+		x, err := newExtractor(".fullname", nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -46,7 +46,7 @@ func TestExtractFullName(t *testing.T) {
 	})
 
 	t.Run("excludeA", func(t *testing.T) {
-		x := newExtractorFullName([]string{"/a"})
+		x := newExtractorFullName([]string{"/a"}, nil)
 		check(t, x, "Test", "Test")
 		check(t, x, "Test/a=123", "Test")
 		check(t, x, "Test/b=123/a=123", "Test/b=123")
@@ -56,17 +56,17 @@ func TestExtractFullName(t *testing.T) {
 	})
 
 	t.Run("excludeName", func(t *testing.T) {
-		x := newExtractorFullName([]string{".name"})
+		x := newExtractorFullName([]string{".name"}, nil)
 		check(t, x, "Test", "*")
 		check(t, x, "Test/a=123", "*/a=123")
-		x = newExtractorFullName([]string{".name", "/a"})
+		x = newExtractorFullName([]string{".name", "/a"}, nil)
 		check(t, x, "Test", "*")
 		check(t, x, "Test/a=123", "*")
 		check(t, x, "Test/a=123/b=123", "*/b=123")
 	})
 
 	t.Run("excludeGomaxprocs", func(t *testing.T) {
-		x := newExtractorFullName([]string{"/gomaxprocs"})
+		x := newExtractorFullName([]string{"/gomaxprocs"}, nil)
 		check(t, x, "Test", "Test")
 		check(t, x, "Test/a=123", "Test/a=123")
 		check(t, x, "Test/a=123-2", "Test/a=123")
@@ -78,7 +78,7 @@ func TestExtractNameKey(t *testing.T) {
 	check := checkNameExtractor
 
 	t.Run("basic", func(t *testing.T) {
-		x, err := newExtractor("/a")
+		x, err := newExtractor("/a", nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -91,7 +91,7 @@ func TestExtractNameKey(t *testing.T) {
 	})
 
 	t.Run("gomaxprocs", func(t *testing.T) {
-		x, err := newExtractor("/gomaxprocs")
+		x, err := newExtractor("/gomaxprocs", nil)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -103,7 +103,7 @@ func TestExtractNameKey(t *testing.T) {
 }
 
 func TestExtractFileKey(t *testing.T) {
-	x, err := newExtractor("file-key")
+	x, err := newExtractor("file-key", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -130,6 +130,6 @@ func TestExtractBadKey(t *testing.T) {
 			t.Errorf("got error %s, want error %s", got, want)
 		}
 	}
-	_, err := newExtractor("")
+	_, err := newExtractor("", nil)
 	check(t, err, "key must not be empty")
 }
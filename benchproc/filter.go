@@ -6,15 +6,31 @@ package benchproc
 
 import (
 	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"golang.org/x/perf/benchfmt"
 	"golang.org/x/perf/benchproc/internal/parse"
+	"golang.org/x/perf/benchunit"
 )
 
 // A Filter filters benchmarks and benchmark observations.
 type Filter struct {
 	// match is the filter function that implements this filter.
 	match filterFn
+
+	// tree is the expression tree of match, used by Explain. Its
+	// root's fn is match.
+	tree *filterNode
+}
+
+// A filterNode is a node in a compiled filter expression.
+type filterNode struct {
+	expr string // Filter expression of this node
+	fn   filterFn
+	subs []*filterNode
 }
 
 // filterFn is a filter function. If it matches individual measurements,
@@ -30,6 +46,12 @@ type filterFn func(res *benchfmt.Result) (mask, bool)
 //
 // To create a filter that matches everything, pass "*" for query.
 func NewFilter(query string) (*Filter, error) {
+	return newFilter(query, nil)
+}
+
+// newFilter constructs a filter whose query may use the sub-name keys
+// defined by schema, which may be nil.
+func newFilter(query string, schema *NameSchema) (*Filter, error) {
 	q, err := parse.ParseFilter(query)
 	if err != nil {
 		return nil, err
@@ -41,20 +63,31 @@ func NewFilter(query string) (*Filter, error) {
 	// We cache extractor functions since it's common to see the
 	// same key multiple times.
 	extractors := make(map[string]extractor)
-	var walk func(q parse.Filter) (filterFn, error)
-	walk = func(q parse.Filter) (filterFn, error) {
-		var err error
-		switch q := q.(type) {
-		case *parse.FilterOp:
+	var walk func(q parse.Filter) (*filterNode, error)
+	var leaf func(q parse.Filter) (filterFn, error)
+	walk = func(q parse.Filter) (*filterNode, error) {
+		if q, ok := q.(*parse.FilterOp); ok {
+			node := &filterNode{expr: q.String(), subs: make([]*filterNode, len(q.Exprs))}
 			subs := make([]filterFn, len(q.Exprs))
 			for i, sub := range q.Exprs {
-				subs[i], err = walk(sub)
+				n, err := walk(sub)
 				if err != nil {
 					return nil, err
 				}
+				node.subs[i], subs[i] = n, n.fn
 			}
-			return filterOp(q.Op, subs), nil
-
+			node.fn = filterOp(q.Op, subs)
+			return node, nil
+		}
+		fn, err := leaf(q)
+		if err != nil {
+			return nil, err
+		}
+		return &filterNode{expr: q.String(), fn: fn}, nil
+	}
+	leaf = func(q parse.Filter) (filterFn, error) {
+		var err error
+		switch q := q.(type) {
 		case *parse.FilterMatch:
 			if q.Key == ".unit" {
 				return func(res *benchfmt.Result) (mask, bool) {
@@ -69,6 +102,21 @@ func NewFilter(query string) (*Filter, error) {
 				}, nil
 			}
 
+			if q.Key == ".value" {
+				if q.Regexp != nil {
+					return valueFilter("", func(v float64) bool {
+						return q.MatchString(strconv.FormatFloat(v, 'g', -1, 64))
+					}), nil
+				}
+				want, unit, err := parseValueLit(q.Lit)
+				if err != nil {
+					return nil, &parse.SyntaxError{Query: query, Off: q.Off, Msg: fmt.Sprintf("cannot match .value with %q: not a number", q.Lit)}
+				}
+				return valueFilter(unit, func(v float64) bool {
+					return v == want
+				}), nil
+			}
+
 			if q.Key == ".config" {
 				return nil, &parse.SyntaxError{Query: query, Off: q.Off, Msg: ".config is only allowed in projections"}
 			}
@@ -76,7 +124,7 @@ func NewFilter(query string) (*Filter, error) {
 			// Construct the extractor.
 			ext := extractors[q.Key]
 			if ext == nil {
-				ext, err = newExtractor(q.Key)
+				ext, err = newExtractor(q.Key, schema)
 				if err != nil {
 					return nil, &parse.SyntaxError{Query: query, Off: q.Off, Msg: err.Error()}
 				}
@@ -87,14 +135,182 @@ func NewFilter(query string) (*Filter, error) {
 			return func(res *benchfmt.Result) (mask, bool) {
 				return nil, q.Match(ext(res))
 			}, nil
+
+		case *parse.FilterCompare:
+			if q.Key == ".unit" || q.Key == ".config" {
+				return nil, &parse.SyntaxError{Query: query, Off: q.Off, Msg: q.Key + " does not support comparisons"}
+			}
+			if q.Key == ".value" {
+				want, unit, err := parseValueLit(q.Val)
+				if err != nil {
+					return nil, &parse.SyntaxError{Query: query, Off: q.Off, Msg: fmt.Sprintf("cannot compare .value with %q: not a number", q.Val)}
+				}
+				test := compareTest(q.Op)
+				return valueFilter(unit, func(v float64) bool {
+					return test(compareFloats(v, want))
+				}), nil
+			}
+			cmp, err := newCompare(q.Op, q.Val)
+			if err != nil {
+				return nil, &parse.SyntaxError{Query: query, Off: q.Off, Msg: err.Error()}
+			}
+			ext := extractors[q.Key]
+			if ext == nil {
+				ext, err = newExtractor(q.Key, schema)
+				if err != nil {
+					return nil, &parse.SyntaxError{Query: query, Off: q.Off, Msg: err.Error()}
+				}
+				extractors[q.Key] = ext
+			}
+			return func(res *benchfmt.Result) (mask, bool) {
+				return nil, cmp(string(ext(res)))
+			}, nil
 		}
 		panic(fmt.Sprintf("unknown query node type %T", q))
 	}
-	f, err := walk(q)
+	tree, err := walk(q)
 	if err != nil {
 		return nil, err
 	}
-	return &Filter{f}, nil
+	return &Filter{tree.fn, tree}, nil
+}
+
+// newCompare returns a function that reports whether a value compares
+// to val according to op.
+//
+// If both the value and val are versions, such as "1.22" or
+// "go1.21.3", they are compared as versions, so any two
+// dot-separated sequences of integers are compared component-wise
+// and "1.10" is after "1.9". Otherwise, if both are numbers (as
+// understood by the "num" sort order), they are compared
+// numerically. Otherwise, the comparison is false.
+func newCompare(op, val string) (func(string) bool, error) {
+	test := compareTest(op)
+	num, numErr := parseExactNum(val)
+	ver, verOK := parseVersion(val)
+	if (numErr != nil || math.IsNaN(num)) && !verOK {
+		return nil, fmt.Errorf("cannot compare with %q: not a number or version", val)
+	}
+	return func(x string) bool {
+		if verOK {
+			if xVer, ok := parseVersion(x); ok {
+				return test(compareVersions(xVer, ver))
+			}
+		}
+		if numErr == nil {
+			if xNum, err := parseExactNum(x); err == nil {
+				return test(compareFloats(xNum, num))
+			}
+		}
+		return false
+	}, nil
+}
+
+// compareTest returns a function that reports whether the result of a
+// three-way comparison satisfies comparison operator op. A comparison
+// result of compareUnordered never satisfies op.
+func compareTest(op string) func(c int) bool {
+	var test func(c int) bool
+	switch op {
+	case "<":
+		test = func(c int) bool { return c < 0 }
+	case "<=":
+		test = func(c int) bool { return c <= 0 }
+	case ">":
+		test = func(c int) bool { return c > 0 }
+	case ">=":
+		test = func(c int) bool { return c >= 0 }
+	default:
+		panic("unknown comparison operator " + op)
+	}
+	return func(c int) bool {
+		return c != compareUnordered && test(c)
+	}
+}
+
+// compareUnordered is the result of compareFloats if either argument
+// is NaN.
+const compareUnordered = math.MinInt
+
+// compareFloats returns -1, 0, or 1 if a is less than, equal to, or
+// greater than b, or compareUnordered if either is NaN.
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	case a == b:
+		return 0
+	}
+	return compareUnordered
+}
+
+// valueFilter returns a filter function that matches the measurements
+// of a result whose tidied values satisfy pred. If unit is not "", it
+// only matches measurements whose tidied unit is unit or, if unit has
+// no denominator, is unit per anything, such as "sec/op" for "sec".
+func valueFilter(unit string, pred func(v float64) bool) filterFn {
+	return func(res *benchfmt.Result) (mask, bool) {
+		m := newMask(len(res.Values))
+		for i := range res.Values {
+			v := &res.Values[i]
+			if unit != "" && v.Unit != unit && (strings.Contains(unit, "/") || !strings.HasPrefix(v.Unit, unit+"/")) {
+				continue
+			}
+			if pred(v.Value) {
+				m.set(i)
+			}
+		}
+		return m, false
+	}
+}
+
+// valueLitRe splits a .value literal into a number and a unit.
+var valueLitRe = regexp.MustCompile(`^([-+]?(?:[0-9]+\.?[0-9]*|\.[0-9]+)(?:[eE][-+]?[0-9]+)?)(.*)$`)
+
+// timeUnits maps the time units accepted in .value literals to seconds.
+var timeUnits = map[string]float64{
+	"ns": 1e-9, "us": 1e-6, "µs": 1e-6, "μs": 1e-6, "ms": 1e-3, "s": 1, "sec": 1,
+}
+
+// parseValueLit parses a .value literal, which is a number, optionally
+// with an SI or IEC suffix as in the "num" sort order, or a quantity
+// with a unit, such as "1ms", "4KiB", or "10MB/s". It returns the
+// value and unit in tidied form (see benchunit.Tidy), so times are in
+// seconds and sizes in bytes, or unit "" for a plain number.
+func parseValueLit(lit string) (float64, string, error) {
+	subs := valueLitRe.FindStringSubmatch(lit)
+	if subs == nil {
+		return 0, "", strconv.ErrSyntax
+	}
+	v, err := strconv.ParseFloat(subs[1], 64)
+	if err != nil {
+		return 0, "", err
+	}
+	unit := subs[2]
+	if unit == "" {
+		return v, "", nil
+	}
+	if n, err := parseExactNum(lit); err == nil && !strings.Contains(unit, "B") {
+		// A number with an SI or IEC suffix, such as "4k".
+		return n, "", nil
+	}
+
+	numer, denom, hasDenom := strings.Cut(unit, "/")
+	if f, ok := timeUnits[numer]; ok {
+		v, numer = v*f, "sec"
+	} else if f, err := parseExactNum("1" + numer); err == nil && strings.HasSuffix(numer, "B") {
+		v, numer = v*f, "B"
+	}
+	if numer == "" || strings.ContainsAny(unit, " \t") || hasDenom && denom == "" {
+		return 0, "", strconv.ErrSyntax
+	}
+	if hasDenom {
+		numer += "/" + denom
+	}
+	v, unit = benchunit.Tidy(v, numer)
+	return v, unit, nil
 }
 
 func filterOp(op parse.Op, subs []filterFn) filterFn {
@@ -6,9 +6,12 @@ package benchproc
 
 import (
 	"fmt"
+	"math"
+	"strings"
 	"testing"
 
 	"golang.org/x/perf/benchfmt"
+	"golang.org/x/perf/benchproc/internal/parse"
 )
 
 func TestFilter(t *testing.T) {
@@ -51,6 +54,14 @@ func TestFilter(t *testing.T) {
 		// Special keys
 		check(t, ".name:Name", ALL)
 		check(t, ".fullname:Name/n1=v3", ALL)
+		// Globs and case-insensitive regexps
+		check(t, ".name:Na*", ALL)
+		check(t, ".name:*x", NONE)
+		check(t, ".fullname:*/n1=v?", ALL)
+		check(t, ".name:/^name$/i", ALL)
+		check(t, ".name:/^name$/", NONE)
+		check(t, ".unit:*/op", ALL)
+		check(t, ".unit:/^b\\//i", 0b10)
 	})
 
 	t.Run("units", func(t *testing.T) {
@@ -60,6 +71,82 @@ func TestFilter(t *testing.T) {
 		check(t, ".unit:foo", 0b00)
 	})
 
+	t.Run("values", func(t *testing.T) {
+		// Values are compared in tidied units, so ns/op and sec/op
+		// inputs compare alike.
+		for _, input := range []string{
+			"BenchmarkX 1 2000000 ns/op 100 B/op 4096 bits",
+			"BenchmarkX 1 0.002 sec/op 0.0001 MB/op 4096 bits",
+		} {
+			r := new(benchfmt.Reader)
+			r.Reset(strings.NewReader(input), "test")
+			if !r.Scan() {
+				t.Fatal(r.Err())
+			}
+			res := r.Result().(*benchfmt.Result)
+			check := func(query string, want uint) {
+				t.Helper()
+				f, err := NewFilter(query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				m, _ := f.Match(res)
+				var got uint
+				for i := 0; i < 3; i++ {
+					if m.Test(i) {
+						got |= 1 << i
+					}
+				}
+				if got != want {
+					t.Errorf("%s: %s: got %03b, want %03b", input, query, got, want)
+				}
+			}
+			check(".value:100", 0b010)
+			check(".value:0.002", 0b001)
+			check(".value:2ms", 0b001)
+			check(".value:2ms/op", 0b001)
+			check(".value:2ms/elem", 0)
+			check(".value:0", 0)
+			check("-.value:0", 0b111)
+			check(".value:/^0\\.002$/", 0b001)
+			check(".value>1ms", 0b001)
+			check(".value>1s", 0)
+			check(".value<=1", 0b001)
+			check(".value>50B", 0b010)
+			check(".value<1kB/op", 0b010)
+			check(".value>=4Ki", 0b100)
+			check(".value>=4096bits", 0b100)
+			check(".value>=100 .unit:B/op", 0b010)
+			check(".value>=100 .unit:ns/op", 0)
+			check(".value<1k OR .unit:ns/op", 0b011)
+		}
+
+		for _, test := range []struct {
+			lit  string
+			v    float64
+			unit string
+		}{
+			{"1e3", 1e3, ""},
+			{"4k", 4000, ""},
+			{"1ms", 1e-3, "sec"},
+			{"250µs/op", 250e-6, "sec/op"},
+			{"4KiB", 4096, "B"},
+			{"10MB/s", 10e6, "B/s"},
+			{"3allocs/op", 3, "allocs/op"},
+		} {
+			v, unit, err := parseValueLit(test.lit)
+			if err != nil || math.Abs(v-test.v) > 1e-12*test.v || unit != test.unit {
+				t.Errorf("parseValueLit(%q) = %v, %q, %v, want %v, %q", test.lit, v, unit, err, test.v, test.unit)
+			}
+		}
+	})
+
+	t.Run("iters", func(t *testing.T) {
+		check(t, ".iters:1", ALL)
+		check(t, ".iters>1", NONE)
+		check(t, ".iters<100", ALL)
+	})
+
 	t.Run("boolean", func(t *testing.T) {
 		check(t, "*", ALL)
 		check(t, "f1:v1 OR f1:v2", ALL)
@@ -71,6 +158,45 @@ func TestFilter(t *testing.T) {
 		check(t, ".unit:(ns/op OR B/op)", 0b11)
 	})
 
+	t.Run("compare", func(t *testing.T) {
+		res := r(t, "Name/size=4k/gomaxprocs=8", "goversion", "go1.21.3", "cores", "16", "minor", "1.9", "patch", "1.10", "name", "a<b")
+		res.Values = []benchfmt.Value{{Value: 1, Unit: "ns/op"}, {Value: 2, Unit: "B/op"}}
+		check := func(t *testing.T, query string, want bool) {
+			t.Helper()
+			f, err := NewFilter(query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m, _ := f.Match(res)
+			if m.All() != want {
+				t.Errorf("%s: got %v, want %v", query, m.All(), want)
+			}
+		}
+		check(t, "/size>=4k", true)
+		check(t, "/size>=4Ki", false)
+		check(t, "/size>3999", true)
+		check(t, "/size<4096", true)
+		check(t, "/size<=4000", true)
+		check(t, "/gomaxprocs<8", false)
+		check(t, "cores>8 /gomaxprocs<=8", true)
+		check(t, "goversion>=go1.21", true)
+		check(t, "goversion<1.21.4", true)
+		check(t, "goversion<go1.21rc1", false)
+		check(t, "goversion>go1.21.3", false)
+		// Dotted numbers compare component-wise.
+		check(t, "minor<1.22", true)
+		check(t, "minor>1.10", false)
+		check(t, "patch>1.9", true)
+		check(t, "patch<=1.10.0", true)
+		check(t, "name:a<b", true)
+		check(t, "name:a>b", false)
+		check(t, "name:/a<b/", true)
+		// Values that are neither numbers nor versions never match.
+		check(t, ".name<1", false)
+		check(t, "missing<1", false)
+		check(t, "-missing<1", true)
+	})
+
 	t.Run("manyUnits", func(t *testing.T) {
 		res := res.Clone()
 		res.Values = make([]benchfmt.Value, 100)
@@ -93,6 +219,28 @@ func TestFilter(t *testing.T) {
 	})
 }
 
+func TestFilterErrors(t *testing.T) {
+	for _, test := range []struct {
+		query, msg string
+	}{
+		{".unit<1", ".unit does not support comparisons"},
+		{".config>=1", ".config does not support comparisons"},
+		{"a<b", `cannot compare with "b": not a number or version`},
+		{".value:x", `cannot match .value with "x": not a number`},
+		{".value<go1.2", `cannot compare .value with "go1.2": not a number`},
+		{".value>1ms/", `cannot compare .value with "1ms/": not a number`},
+	} {
+		_, err := NewFilter(test.query)
+		if err == nil {
+			t.Errorf("%s: want error %q, got nil", test.query, test.msg)
+			continue
+		}
+		if se, ok := err.(*parse.SyntaxError); !ok || se.Msg != test.msg {
+			t.Errorf("%s: want error %q, got %v", test.query, test.msg, err)
+		}
+	}
+}
+
 func TestMatch(t *testing.T) {
 	check := func(m Match, all, any bool) {
 		t.Helper()
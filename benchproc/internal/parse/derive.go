@@ -0,0 +1,213 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A Derivation is a parsed derived unit definition of the form
+// "unit=expr".
+type Derivation struct {
+	// Unit is the unit of the derived measurement.
+	Unit string
+	// Expr computes the derived measurement.
+	Expr Expr
+}
+
+// String returns d as a valid derived unit definition.
+func (d *Derivation) String() string {
+	return d.Unit + "=" + d.Expr.String()
+}
+
+// An Expr is a node in an arithmetic expression. It can either be an
+// ExprOp or an ExprOperand.
+type Expr interface {
+	isExpr()
+	String() string
+}
+
+// An ExprOp is a binary arithmetic operator in an Expr tree.
+type ExprOp struct {
+	// Op is one of '+', '-', '*', or '/'.
+	Op   byte
+	L, R Expr
+}
+
+func (e *ExprOp) isExpr() {}
+
+func (e *ExprOp) String() string {
+	return "(" + e.L.String() + " " + string(e.Op) + " " + e.R.String() + ")"
+}
+
+// An ExprOperand is a leaf in an Expr tree.
+type ExprOperand struct {
+	// Kind is 'n' for a number literal, 'k' for a "/" or
+	// "."-prefixed key, or 'u' for a bare word, which may be either
+	// a unit or a file configuration key.
+	Kind byte
+	// Tok is the literal text of this operand.
+	Tok string
+	// Num is the value of a number literal.
+	Num float64
+	// Off is the byte offset of the operand in the original
+	// definition, for error reporting.
+	Off int
+}
+
+func (e *ExprOperand) isExpr() {}
+
+func (e *ExprOperand) String() string {
+	return e.Tok
+}
+
+// ParseDerivation parses a derived unit definition of the form
+// "unit=expr".
+//
+// Since units may themselves contain operator characters, operators
+// and operands in expr must be separated by spaces. Parentheses may
+// be attached to the beginning or end of an operand.
+func ParseDerivation(q string) (*Derivation, error) {
+	eq := strings.IndexByte(q, '=')
+	if eq < 0 {
+		return nil, &SyntaxError{q, 0, "expected unit=expression"}
+	}
+	unit := strings.TrimSpace(q[:eq])
+	if unit == "" {
+		return nil, &SyntaxError{q, 0, "missing unit"}
+	}
+	if strings.IndexFunc(unit, unicode.IsSpace) >= 0 {
+		return nil, &SyntaxError{q, 0, "unit must not contain spaces"}
+	}
+
+	p := exprParser{q: q, toks: tokenizeExpr(q, eq+1)}
+	expr := p.expr()
+	if p.err == nil && p.pos < len(p.toks) {
+		p.error(p.toks[p.pos].off, "unexpected "+strconv.Quote(p.toks[p.pos].tok))
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &Derivation{unit, expr}, nil
+}
+
+type exprTok struct {
+	tok string
+	off int
+}
+
+// tokenizeExpr splits q[start:] into space-separated words, further
+// splitting parentheses from the beginning and end of each word.
+func tokenizeExpr(q string, start int) []exprTok {
+	var toks []exprTok
+	for i := start; i < len(q); {
+		r, n := utf8.DecodeRuneInString(q[i:])
+		if unicode.IsSpace(r) {
+			i += n
+			continue
+		}
+		end := i + strings.IndexFunc(q[i:], unicode.IsSpace)
+		if end < i {
+			end = len(q)
+		}
+		word, off := q[i:end], i
+		for len(word) > 1 && word[0] == '(' {
+			toks = append(toks, exprTok{"(", off})
+			word, off = word[1:], off+1
+		}
+		var closes int
+		for len(word) > 1 && word[len(word)-1] == ')' {
+			word = word[:len(word)-1]
+			closes++
+		}
+		toks = append(toks, exprTok{word, off})
+		for j := 0; j < closes; j++ {
+			toks = append(toks, exprTok{")", off + len(word) + j})
+		}
+		i = end
+	}
+	return toks
+}
+
+type exprParser struct {
+	q    string
+	toks []exprTok
+	pos  int
+	err  *SyntaxError
+}
+
+func (p *exprParser) error(off int, msg string) {
+	if p.err == nil {
+		p.err = &SyntaxError{p.q, off, msg}
+	}
+}
+
+// peek returns the next token, or an empty token at the end of the
+// input.
+func (p *exprParser) peek() exprTok {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return exprTok{"", len(p.q)}
+}
+
+func isExprOp(tok string, ops string) bool {
+	return len(tok) == 1 && strings.Contains(ops, tok)
+}
+
+// expr = term {("+" | "-") term}
+func (p *exprParser) expr() Expr {
+	e := p.term()
+	for p.err == nil && isExprOp(p.peek().tok, "+-") {
+		op := p.peek().tok[0]
+		p.pos++
+		e = &ExprOp{op, e, p.term()}
+	}
+	return e
+}
+
+// term = factor {("*" | "/") factor}
+func (p *exprParser) term() Expr {
+	e := p.factor()
+	for p.err == nil && isExprOp(p.peek().tok, "*/") {
+		op := p.peek().tok[0]
+		p.pos++
+		e = &ExprOp{op, e, p.factor()}
+	}
+	return e
+}
+
+// factor = "(" expr ")" | operand
+func (p *exprParser) factor() Expr {
+	t := p.peek()
+	switch {
+	case t.tok == "":
+		p.error(t.off, "expected operand")
+		return nil
+	case t.tok == "(":
+		p.pos++
+		e := p.expr()
+		if p.peek().tok != ")" {
+			p.error(p.peek().off, "missing \")\"")
+			return nil
+		}
+		p.pos++
+		return e
+	case t.tok == ")" || isExprOp(t.tok, "+-*/"):
+		p.error(t.off, "expected operand")
+		return nil
+	}
+	p.pos++
+	if v, err := strconv.ParseFloat(t.tok, 64); err == nil {
+		return &ExprOperand{'n', t.tok, v, t.off}
+	}
+	if t.tok[0] == '/' || t.tok[0] == '.' {
+		return &ExprOperand{'k', t.tok, 0, t.off}
+	}
+	return &ExprOperand{'u', t.tok, 0, t.off}
+}
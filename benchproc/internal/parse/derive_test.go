@@ -0,0 +1,45 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse
+
+import "testing"
+
+func TestParseDerivation(t *testing.T) {
+	check := func(def string, want string) {
+		t.Helper()
+		d, err := ParseDerivation(def)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", def, err)
+		} else if got := d.String(); got != want {
+			t.Errorf("%s: got %s, want %s", def, got, want)
+		}
+	}
+	checkErr := func(def, error string, pos int) {
+		t.Helper()
+		_, err := ParseDerivation(def)
+		if se, _ := err.(*SyntaxError); se == nil || se.Msg != error || se.Off != pos {
+			t.Errorf("%s: want error %s at %d; got %s", def, error, pos, err)
+		}
+	}
+
+	check("ns/elem=sec/op / /size", "ns/elem=(sec/op / /size)")
+	check(" B/alloc = B/op / allocs/op ", "B/alloc=(B/op / allocs/op)")
+	check("x=a + b * c", "x=(a + (b * c))")
+	check("x=a - b - c", "x=((a - b) - c)")
+	check("x=(a + b) * c", "x=((a + b) * c)")
+	check("x=((a))", "x=a")
+	check("x=a / (b - 1)", "x=(a / (b - 1))")
+	check("x=2.5 * .iters", "x=(2.5 * .iters)")
+
+	checkErr("sec/op", "expected unit=expression", 0)
+	checkErr("=sec/op", "missing unit", 0)
+	checkErr("a b=sec/op", "unit must not contain spaces", 0)
+	checkErr("x=", "expected operand", 2)
+	checkErr("x=a +", "expected operand", 5)
+	checkErr("x=a b", "unexpected \"b\"", 4)
+	checkErr("x=(a + b", "missing \")\"", 8)
+	checkErr("x=a)", "unexpected \")\"", 3)
+	checkErr("x=* a", "expected operand", 2)
+}
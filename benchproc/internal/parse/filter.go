@@ -4,7 +4,11 @@
 
 package parse
 
-import "strconv"
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
 
 // ParseFilter parses a filter expression into a Filter tree.
 func ParseFilter(q string) (Filter, error) {
@@ -72,7 +76,7 @@ loop:
 }
 
 func (p *parser) match(start tokenizer) (Filter, tokenizer) {
-	tok, rest := start.keyOrOp()
+	tok, rest := start.keyOrCompare()
 	switch tok.Kind {
 	case '(':
 		q, rest := p.expr(rest)
@@ -91,9 +95,18 @@ func (p *parser) match(start tokenizer) (Filter, tokenizer) {
 	case 'w', 'q':
 		off := tok.Off
 		key := tok.Tok
-		op, toks2 := rest.keyOrOp()
-		if op.Kind != ':' {
-			// TODO: Support other operators
+		op, toks2 := rest.keyOrCompare()
+		switch op.Kind {
+		case '<', '>', 'L', 'G':
+			// Comparison.
+			val, rest := toks2.valueOrOp()
+			if val.Kind != 'w' && val.Kind != 'q' {
+				return nil, p.error(toks2, "expected value")
+			}
+			return &FilterCompare{key, op.Tok, val.Tok, off}, rest
+		case ':':
+			// Match.
+		default:
 			return nil, p.error(start, "expected key:value")
 		}
 		rest = toks2
@@ -134,13 +147,40 @@ func (p *parser) match(start tokenizer) (Filter, tokenizer) {
 
 func (p *parser) mkMatch(off int, key string, val tok) Filter {
 	switch val.Kind {
-	case 'w', 'q':
+	case 'w':
+		if strings.ContainsAny(val.Tok, "*?") {
+			// Glob match.
+			return &FilterMatch{key, globRegexp(val.Tok), val.Tok, true, off}
+		}
 		// Literal match.
-		return &FilterMatch{key, nil, val.Tok, off}
+		return &FilterMatch{key, nil, val.Tok, false, off}
+	case 'q':
+		// Literal match. Quoting disables globbing.
+		return &FilterMatch{key, nil, val.Tok, false, off}
 	case 'r':
 		// Regexp match.
-		return &FilterMatch{key, val.Regexp, "", off}
+		return &FilterMatch{key, val.Regexp, "", false, off}
 	default:
 		panic("non-word token")
 	}
 }
+
+// globRegexp compiles a glob pattern into an anchored regexp. In the
+// pattern, "*" matches any sequence of characters (including "/") and
+// "?" matches any single character.
+func globRegexp(glob string) *regexp.Regexp {
+	var buf strings.Builder
+	buf.WriteString("^(?s:")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			buf.WriteString(".*")
+		case '?':
+			buf.WriteString(".")
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	buf.WriteString(")$")
+	return regexp.MustCompile(buf.String())
+}
@@ -71,4 +71,37 @@ func TestParseFilter(t *testing.T) {
 	checkErr(`a:(b AND c)`, "value list must be separated by OR", 5)
 	checkErr(`a:(b OR AND)`, "expected value", 8)
 	checkErr(`a:()`, "expected value", 3)
+
+	// Case-insensitive regexp match
+	check("a:/b/i", "a:/(?i)b/")
+	check("a:/b/i OR c:d", "(a:/(?i)b/ OR c:d)")
+	check("a:(/b/i OR c)", "(a:/(?i)b/ OR a:c)")
+	checkErr("a:/b/ic", "regexp must be followed by space or an operator (unescaped \"/\"?)", 6)
+	checkErr("a:/b/I", "regexp must be followed by space or an operator (unescaped \"/\"?)", 5)
+
+	// Glob match
+	check(`a:b*`, `a:b*`)
+	check(`a:*b`, `a:*b`)
+	check(`a:*`, `a:*`)
+	check(`a:b?c`, `a:b?c`)
+	check(`a:"b*"`, `a:"b*"`)
+	check(`a:(*b OR c*)`, `(a:*b OR a:c*)`)
+	check(`a:*b *`, `(a:*b AND *)`)
+
+	// Comparisons
+	check(`a<b`, `a<b`)
+	check(`a <= 4k`, `a<=4k`)
+	check(`a>"b c"`, `a>"b c"`)
+	check(`/size>=4k .unit:sec/op`, `(/size>=4k AND .unit:sec/op)`)
+	check(`-a<1 OR b>2`, `(-a<1 OR b>2)`)
+	check(`"a<b":c`, `"a<b":c`)
+	checkErr(`a<`, "expected value", 2)
+	checkErr(`a</b/`, "expected value", 2)
+	checkErr(`a<(b OR c)`, "expected value", 2)
+	checkErr(`a<=:b`, "expected value", 3)
+	// Elsewhere, "<" and ">" are part of words.
+	check(`a:b<c`, `a:"b<c"`)
+	check(`a:(b>c OR d)`, `(a:"b>c" OR a:d)`)
+	check(`a:/b>c/`, `a:/b>c/`)
+	check(`a:b a<=1`, `(a:b AND a<=1)`)
 }
@@ -6,6 +6,7 @@ package parse
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 )
 
@@ -27,24 +28,89 @@ type Field struct {
 	// according to their order in this list.
 	Fixed []string
 
+	// OrderArgs gives the arguments of a named sort order, as in
+	// "key@order(arg arg ...)". It is nil if the order has no
+	// argument list.
+	OrderArgs []string
+
+	// Rewrites is a sequence of rewrites to apply to this field's
+	// value before ordering it.
+	Rewrites []Rewrite
+
 	// KeyOff and OrderOff give the byte offsets of the key and
 	// order, for error reporting.
 	KeyOff, OrderOff int
 }
 
+// A Rewrite maps the value of a Field to a new value. It is either an
+// "alias" rewrite, which maps specific values to new values, or an
+// "re" rewrite, which maps values to part of the value matched by a
+// regexp. In either case, values that aren't matched by the rewrite
+// are left unchanged.
+type Rewrite struct {
+	// Alias is the list of value mappings for an "alias" rewrite.
+	Alias []Alias
+
+	// Regexps is the list of regexps for an "re" rewrite. A value
+	// is rewritten using the first regexp that matches it. If the
+	// regexp has a capturing group, the value is rewritten to the
+	// text matched by the first group; otherwise, it is rewritten
+	// to the text matched by the whole regexp.
+	Regexps []*regexp.Regexp
+
+	// Off gives the byte offset of this rewrite, for error
+	// reporting.
+	Off int
+}
+
+// An Alias maps value From to value To.
+type Alias struct {
+	From, To string
+}
+
+// String returns r as a valid projection rewrite, without the leading
+// "@".
+func (r Rewrite) String() string {
+	var words []string
+	if r.Regexps != nil {
+		for _, re := range r.Regexps {
+			words = append(words, "/"+re.String()+"/")
+		}
+		return fmt.Sprintf("re(%s)", strings.Join(words, " "))
+	}
+	for _, a := range r.Alias {
+		words = append(words, quoteWord(a.From+"="+a.To))
+	}
+	return fmt.Sprintf("alias(%s)", strings.Join(words, " "))
+}
+
 // String returns Projection as a valid projection expression.
 func (p Field) String() string {
+	var buf strings.Builder
+	buf.WriteString(quoteWord(p.Key))
+	for _, r := range p.Rewrites {
+		buf.WriteString("@")
+		buf.WriteString(r.String())
+	}
 	switch p.Order {
 	case "first":
-		return quoteWord(p.Key)
 	case "fixed":
 		words := make([]string, 0, len(p.Fixed))
 		for _, word := range p.Fixed {
 			words = append(words, quoteWord(word))
 		}
-		return fmt.Sprintf("%s@(%s)", quoteWord(p.Key), strings.Join(words, " "))
+		fmt.Fprintf(&buf, "@(%s)", strings.Join(words, " "))
+	default:
+		fmt.Fprintf(&buf, "@%s", quoteWord(p.Order))
+		if p.OrderArgs != nil {
+			words := make([]string, 0, len(p.OrderArgs))
+			for _, word := range p.OrderArgs {
+				words = append(words, quoteWord(word))
+			}
+			fmt.Fprintf(&buf, "(%s)", strings.Join(words, " "))
+		}
 	}
-	return fmt.Sprintf("%s@%s", quoteWord(p.Key), quoteWord(p.Order))
+	return buf.String()
 }
 
 // ParseProjection parses a projection expression into a tuple of
@@ -88,47 +154,129 @@ func parseField(toks tokenizer) (Field, tokenizer) {
 	f.Key = key.Tok
 	f.KeyOff = key.Off
 
-	// Consume optional sort order.
+	// Consume optional rewrites and sort order.
 	f.Order = "first"
 	f.OrderOff = key.Off + len(key.Tok)
-	sep, toks2 := toks.keyOrOp()
-	if sep.Kind != '@' {
-		// No sort order.
-		return f, toks
+	for {
+		sep, toks2 := toks.keyOrOp()
+		if sep.Kind != '@' {
+			// No sort order.
+			return f, toks
+		}
+
+		// Is it a rewrite?
+		name, toks3 := toks2.keyOrOp()
+		if open, _ := toks3.keyOrOp(); name.Kind == 'w' && (name.Tok == "alias" || name.Tok == "re") && open.Kind == '(' {
+			var r Rewrite
+			r, toks = parseRewrite(toks2)
+			f.Rewrites = append(f.Rewrites, r)
+			continue
+		}
+		toks = toks2
+		break
 	}
-	toks = toks2
 
 	// Is it a named sort order?
 	order, toks2 := toks.keyOrOp()
 	f.OrderOff = order.Off
 	if order.Kind == 'w' || order.Kind == 'q' {
 		f.Order = order.Tok
-		return f, toks2
+		toks = toks2
+		// With arguments?
+		if open, toks2 := toks.keyOrOp(); open.Kind == '(' {
+			f.OrderArgs, toks = parseWords(toks2)
+			if len(f.OrderArgs) == 0 {
+				// Consume the ")" of an empty list.
+				_, toks = toks.keyOrOp()
+			}
+		}
+		return f, toks
 	}
 	// Or a fixed sort order?
 	if order.Kind == '(' {
 		f.Order = "fixed"
-		toks = toks2
-		for {
-			t, toks2 := toks.keyOrOp()
-			if t.Kind == 'w' || t.Kind == 'q' {
+		f.Fixed, toks = parseWords(toks2)
+		if len(f.Fixed) == 0 && toks.errt.err == nil {
+			_, toks = toks.error("nothing to match")
+		}
+		return f, toks
+	}
+	// Bad sort order syntax.
+	_, toks = toks.error("expected named sort order or parenthesized list")
+	return f, toks
+}
+
+// parseWords parses a list of words up to and including the closing
+// ")". The caller has already consumed the opening "(". If the list is
+// empty, it returns a non-nil, empty slice and a tokenizer positioned
+// at the ")".
+func parseWords(toks tokenizer) ([]string, tokenizer) {
+	words := []string{}
+	for {
+		t, toks2 := toks.keyOrOp()
+		if t.Kind == 'w' || t.Kind == 'q' {
+			toks = toks2
+			words = append(words, t.Tok)
+		} else if t.Kind == ')' {
+			if len(words) > 0 {
 				toks = toks2
-				f.Fixed = append(f.Fixed, t.Tok)
-			} else if t.Kind == ')' {
-				if len(f.Fixed) == 0 {
-					_, toks = toks.error("nothing to match")
-				} else {
-					toks = toks2
-				}
-				break
+			}
+			return words, toks
+		} else {
+			_, toks = toks.error("missing )")
+			return words, toks
+		}
+	}
+}
+
+// parseRewrite parses an "alias(...)" or "re(...)" rewrite. The caller
+// has already checked that toks starts with "alias" or "re" followed
+// by "(".
+func parseRewrite(toks tokenizer) (Rewrite, tokenizer) {
+	var r Rewrite
+	name, toks := toks.keyOrOp()
+	r.Off = name.Off
+	_, toks = toks.keyOrOp() // Consume "("
+	n := 0
+	for ; ; n++ {
+		var t tok
+		var toks2 tokenizer
+		if name.Tok == "re" {
+			t, toks2 = toks.valueOrOp()
+		} else {
+			// Alias values may begin with "/", so don't
+			// parse regexps.
+			t, toks2 = toks.keyOrOp()
+		}
+		if t.Kind == ')' {
+			if n == 0 {
+				_, toks = toks.error("nothing to match")
 			} else {
+				toks = toks2
+			}
+			break
+		}
+		if name.Tok == "re" {
+			if t.Kind != 'r' {
+				_, toks = toks.error("expected regexp")
+				break
+			}
+			r.Regexps = append(r.Regexps, t.Regexp)
+		} else {
+			if t.Kind != 'w' && t.Kind != 'q' {
 				_, toks = toks.error("missing )")
 				break
 			}
+			// Split at the last "=", so the original value
+			// may contain "=".
+			i := strings.LastIndexByte(t.Tok, '=')
+			if i < 0 {
+				_, toks = toks.error("expected value=alias")
+				break
+			}
+			r.Alias = append(r.Alias, Alias{t.Tok[:i], t.Tok[i+1:]})
 		}
-		return f, toks
+		toks = toks2
 	}
-	// Bad sort order syntax.
-	_, toks = toks.error("expected named sort order or parenthesized list")
-	return f, toks
+	return r, toks
 }
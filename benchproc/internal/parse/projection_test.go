@@ -49,4 +49,28 @@ func TestParseProjection(t *testing.T) {
 	checkErr("a@(", "missing )", 3)
 	checkErr("a@(,", "missing )", 3)
 	checkErr("a@()", "nothing to match", 3)
+
+	// Order arguments
+	check("a@git(/path/to/repo), b", "a@git(/path/to/repo)", "b")
+	check("a@git(x \"y z\")", `a@git(x "y z")`)
+	check("a@git()", "a@git()")
+	check("a@alias(x=y)@git(.)", "a@alias(x=y)@git(.)")
+	checkErr("a@git(", "missing )", 6)
+	checkErr("a@git(x", "missing )", 7)
+
+	// Rewrites
+	check("a@alias(x=y \"p q=r\")", `a@alias(x=y "p q=r")`)
+	check("a@alias(x=y)@num", "a@alias(x=y)@num")
+	check("a@alias(x=y)@(y z)", "a@alias(x=y)@(y z)")
+	check("a@alias(/n=1=one)", "a@alias(/n=1=one)")
+	check("a@alias(x=)", "a@alias(x=)")
+	check("a@re(/(b)c/ /d/i)", "a@re(/(b)c/ /(?i)d/)")
+	check("a@re(/b/)@alias(b=c), d", "a@re(/b/)@alias(b=c)", "d")
+	check("a@alias", "a@alias")
+	check("a@re", "a@re")
+	checkErr("a@alias()", "nothing to match", 8)
+	checkErr("a@alias(x)", "expected value=alias", 8)
+	checkErr("a@alias(x=y", "missing )", 11)
+	checkErr("a@re(x)", "expected regexp", 5)
+	checkErr("a@re(/x/", "expected regexp", 8)
 }
@@ -51,8 +51,10 @@ func (t *errorTracker) error(q string, msg string) {
 type tok struct {
 	// Kind specifies the category of this token. It is either 'w'
 	// or 'q' for an unquoted or quoted word, respectively, 'r'
-	// for a regexp, an operator character, or 0 for the
-	// end-of-string token.
+	// for a regexp, an operator character, 'L' or 'G' for the
+	// two-character operators "<=" or ">=", or 0 for the
+	// end-of-string token. The comparison operators are only
+	// tokenized by keyOrCompare.
 	Kind   byte
 	Off    int    // Byte offset of the beginning of this token
 	Tok    string // Literal token contents; quoted words are unescaped
@@ -72,6 +74,13 @@ func isOp(ch rune) bool {
 	return ch == '(' || ch == ')' || ch == ':' || ch == '@' || ch == ','
 }
 
+// isCompareOp reports whether ch begins a comparison operator. These
+// are only operators around filter keys, so values and other keys may
+// contain them.
+func isCompareOp(ch rune) bool {
+	return ch == '<' || ch == '>'
+}
+
 // At the beginning of a word, we accept "-" and "*" as operators,
 // but in the middle of words we treat them as part of the word.
 func isStartOp(ch rune) bool {
@@ -92,13 +101,21 @@ func isSpace(q string) int {
 // keyOrOp returns the next key or operator token.
 // A key may be a bare word or a quoted word.
 func (t *tokenizer) keyOrOp() (tok, tokenizer) {
-	return t.next(false)
+	return t.next(false, false)
+}
+
+// keyOrCompare is like keyOrOp, but also returns the comparison
+// operators "<", "<=", ">", and ">=", and a bare word key ends at
+// them. It's used for a filter key and the operator that follows it.
+func (t *tokenizer) keyOrCompare() (tok, tokenizer) {
+	return t.next(false, true)
 }
 
 // valueOrOp returns the next value or operator token.
-// A value may be a bare word, a quoted word, or a regexp.
+// A value may be a bare word, a quoted word, or a regexp. Unlike keys,
+// a bare word value may begin with "*".
 func (t *tokenizer) valueOrOp() (tok, tokenizer) {
-	return t.next(true)
+	return t.next(true, false)
 }
 
 // end asserts that t has reached the end of the token stream. If it
@@ -111,9 +128,21 @@ func (t *tokenizer) end() tokenizer {
 	return *t
 }
 
-func (t *tokenizer) next(allowRegexp bool) (tok, tokenizer) {
+func (t *tokenizer) next(allowRegexp, compare bool) (tok, tokenizer) {
 	for len(t.q) > 0 {
-		if isStartOp(rune(t.q[0])) {
+		if compare && isCompareOp(rune(t.q[0])) {
+			if strings.HasPrefix(t.q[1:], "=") {
+				kind := byte('L')
+				if t.q[0] == '>' {
+					kind = 'G'
+				}
+				return t.tok(kind, t.q[:2], t.q[2:])
+			}
+			return t.tok(t.q[0], t.q[:1], t.q[1:])
+		} else if allowRegexp && t.q[0] == '*' {
+			// In a value, "*" starts a glob pattern.
+			return t.bareWord(false)
+		} else if isStartOp(rune(t.q[0])) {
 			return t.tok(t.q[0], t.q[:1], t.q[1:])
 		} else if n := isSpace(t.q); n > 0 {
 			t.q = t.q[n:]
@@ -122,7 +151,7 @@ func (t *tokenizer) next(allowRegexp bool) (tok, tokenizer) {
 		} else if t.q[0] == '"' {
 			return t.quotedWord()
 		} else {
-			return t.bareWord()
+			return t.bareWord(compare)
 		}
 	}
 	// Add an EOF token. This eliminates the need for lots of
@@ -157,14 +186,16 @@ func (t *tokenizer) quotedWord() (tok, tokenizer) {
 	return t.tok('q', word, t.q[pos+1:])
 }
 
-func (t *tokenizer) bareWord() (tok, tokenizer) {
+// bareWord returns the bare word at the start of t. If compare is
+// set, the word also ends at a comparison operator.
+func (t *tokenizer) bareWord(compare bool) (tok, tokenizer) {
 	// Consume until a space or operator. We only take "-"
 	// as an operator immediately following another space
 	// or operator so things like "foo-bar" work as
 	// expected.
 	end := len(t.q)
 	for i, r := range t.q {
-		if unicode.IsSpace(r) || isOp(r) {
+		if unicode.IsSpace(r) || isOp(r) || compare && isCompareOp(r) {
 			end = i
 			break
 		}
@@ -188,7 +219,7 @@ func quoteWord(s string) string {
 		case '"', ' ', '\a', '\b':
 			return strconv.Quote(s)
 		}
-		if isOp(r) || unicode.IsSpace(r) || (i == 0 && (r == '-' || r == '*')) {
+		if isOp(r) || isCompareOp(r) || unicode.IsSpace(r) || (i == 0 && (r == '-' || r == '*')) {
 			return strconv.Quote(s)
 		}
 	}
@@ -204,14 +235,22 @@ func (t *tokenizer) regexp() (tok, tokenizer) {
 		return t.error(err.Error())
 	}
 
-	r, err := regexp.Compile(expr)
+	// Check for flags after the close "/". The only supported
+	// flag is "i", for case-insensitive matching.
+	q2 := rest[1:]
+	re := expr
+	if strings.HasPrefix(q2, "i") {
+		re = "(?i)" + expr
+		q2 = q2[1:]
+	}
+
+	r, err := regexp.Compile(re)
 	if err != nil {
 		return t.error(err.Error())
 	}
 
 	// To avoid confusion when "/" appears in the regexp itself,
 	// we require space or an operator after the close "/".
-	q2 := rest[1:]
 	if !(q2 == "" || unicode.IsSpace(rune(q2[0])) || isStartOp(rune(q2[0]))) {
 		t.q = q2
 		return t.error("regexp must be followed by space or an operator (unescaped \"/\"?)")
@@ -7,11 +7,12 @@ package parse
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
 // A Filter is a node in the boolean filter. It can either be a
-// FilterOp or a FilterMatch.
+// FilterOp, a FilterMatch, or a FilterCompare.
 type Filter interface {
 	isFilter()
 	String() string
@@ -27,8 +28,12 @@ type FilterMatch struct {
 	// match against Lit.
 	Regexp *regexp.Regexp
 	// Lit is the literal value to match against the value if Regexp
-	// is nil.
+	// is nil. If Glob is set, Lit is instead the glob pattern that
+	// Regexp was compiled from.
 	Lit string
+	// Glob indicates that this is a glob match. Glob matches
+	// are implemented by Regexp.
+	Glob bool
 
 	// Off is the byte offset of the key in the original query,
 	// for error reporting.
@@ -37,9 +42,18 @@ type FilterMatch struct {
 
 func (q *FilterMatch) isFilter() {}
 func (q *FilterMatch) String() string {
+	if q.Glob {
+		// Lit came from a bare word, so it doesn't need
+		// quoting, and quoting would make it a literal.
+		return quoteWord(q.Key) + ":" + q.Lit
+	}
 	if q.Regexp != nil {
 		return quoteWord(q.Key) + ":/" + q.Regexp.String() + "/"
 	}
+	if strings.ContainsAny(q.Lit, "*?") {
+		// Quote it so it doesn't parse as a glob.
+		return quoteWord(q.Key) + ":" + strconv.Quote(q.Lit)
+	}
 	return quoteWord(q.Key) + ":" + quoteWord(q.Lit)
 }
 
@@ -59,6 +73,25 @@ func (q *FilterMatch) MatchString(value string) bool {
 	return q.Lit == value
 }
 
+// A FilterCompare is a leaf in a Filter tree that compares a specific
+// key against a value using an ordering comparison.
+type FilterCompare struct {
+	Key string
+	// Op is the comparison operator: "<", "<=", ">", or ">=".
+	Op string
+	// Val is the value to compare against.
+	Val string
+	// Off is the byte offset of the key in the original query,
+	// for error reporting.
+	Off int
+}
+
+func (q *FilterCompare) isFilter() {}
+
+func (q *FilterCompare) String() string {
+	return quoteWord(q.Key) + q.Op + quoteWord(q.Val)
+}
+
 // A FilterOp is a boolean operator in the Filter tree. OpNot must have
 // exactly one child node. OpAnd and OpOr may have zero or more child nodes.
 type FilterOp struct {
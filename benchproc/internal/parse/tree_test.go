@@ -0,0 +1,45 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parse
+
+import "testing"
+
+func TestFilterMatch(t *testing.T) {
+	check := func(query, value string, want bool) {
+		t.Helper()
+		q, err := ParseFilter(query)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", query, err)
+		}
+		m, ok := q.(*FilterMatch)
+		if !ok {
+			t.Fatalf("%s: want *FilterMatch, got %T", query, q)
+		}
+		if got := m.MatchString(value); got != want {
+			t.Errorf("%s: MatchString(%q) = %v, want %v", query, value, got, want)
+		}
+		if got := m.Match([]byte(value)); got != want {
+			t.Errorf("%s: Match(%q) = %v, want %v", query, value, got, want)
+		}
+	}
+
+	check(`a:Encode`, "Encode", true)
+	check(`a:Encode`, "EncodeJSON", false)
+
+	check(`a:Encode*`, "Encode", true)
+	check(`a:Encode*`, "EncodeJSON/size=4k", true)
+	check(`a:Encode*`, "XEncode", false)
+	check(`a:*JSON`, "EncodeJSON", true)
+	check(`a:*JSON`, "EncodeJSONx", false)
+	check(`a:En?ode`, "Encode", true)
+	check(`a:En?ode`, "Enode", false)
+	check(`a:a.b*`, "a.bc", true)
+	check(`a:a.b*`, "axbc", false)
+	check(`a:"Encode*"`, "EncodeJSON", false)
+	check(`a:"Encode*"`, "Encode*", true)
+
+	check(`a:/^enc/i`, "EncodeJSON", true)
+	check(`a:/^enc/`, "EncodeJSON", false)
+}
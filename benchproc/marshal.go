@@ -0,0 +1,312 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Keys are interned in their Projection, so they can't be decoded on
+// their own. Instead, a Projection's field layout can be encoded with
+// Projection.MarshalJSON and reconstructed with
+// ProjectionParser.UnmarshalProjection, and Keys can then be decoded
+// into that Projection.
+
+// MarshalText encodes k as a space-separated sequence of field:value
+// pairs in field order, omitting empty values. This is the same as
+// String, except that field names and values are double-quoted Go
+// strings if they are empty or contain spaces, colons, double quotes,
+// or non-printable characters. The result can be decoded by
+// Projection.KeyFromText.
+//
+// MarshalText returns an error if k is the zero Key.
+func (k Key) MarshalText() ([]byte, error) {
+	if k.IsZero() {
+		return nil, fmt.Errorf("cannot marshal zero Key")
+	}
+	var buf []byte
+	k.each(func(name, val string) {
+		if len(buf) > 0 {
+			buf = append(buf, ' ')
+		}
+		buf = appendKeyWord(buf, name)
+		buf = append(buf, ':')
+		buf = appendKeyWord(buf, val)
+	})
+	return buf, nil
+}
+
+// MarshalJSON encodes k as a JSON object mapping field names to values
+// in field order, omitting empty values. The zero Key is encoded as
+// null. The result can be decoded by Projection.KeyFromJSON.
+func (k Key) MarshalJSON() ([]byte, error) {
+	if k.IsZero() {
+		return []byte("null"), nil
+	}
+	buf := []byte{'{'}
+	first := true
+	var err error
+	k.each(func(name, val string) {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		var b []byte
+		if b, err = json.Marshal(name); err != nil {
+			return
+		}
+		buf = append(buf, b...)
+		buf = append(buf, ':')
+		if b, err = json.Marshal(val); err != nil {
+			return
+		}
+		buf = append(buf, b...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, '}'), nil
+}
+
+// each calls f for each non-empty field of k in field order.
+func (k Key) each(f func(name, val string)) {
+	for _, field := range k.k.proj.FlattenedFields() {
+		if field.idx >= len(k.k.vals) {
+			continue
+		}
+		if val := k.k.vals[field.idx]; val != "" {
+			f(field.Name, val)
+		}
+	}
+}
+
+// appendKeyWord appends s to buf, quoting it if necessary.
+func appendKeyWord(buf []byte, s string) []byte {
+	if s == "" || strings.IndexFunc(s, func(r rune) bool {
+		return r == ' ' || r == ':' || r == '"' || !unicode.IsPrint(r)
+	}) >= 0 {
+		return strconv.AppendQuote(buf, s)
+	}
+	return append(buf, s...)
+}
+
+// KeyFromText decodes a Key encoded by Key.MarshalText into p. Field
+// names must refer to fields of p, except that if p has a tuple field
+// such as ".config", unknown field names are added to it, as Project
+// would.
+func (p *Projection) KeyFromText(text []byte) (Key, error) {
+	s := string(text)
+	var pairs [][2]string
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			break
+		}
+		var name, val string
+		var err error
+		if name, s, err = cutKeyWord(s); err != nil {
+			return Key{}, err
+		}
+		if !strings.HasPrefix(s, ":") {
+			return Key{}, fmt.Errorf("missing \":\" after field %q", name)
+		}
+		if val, s, err = cutKeyWord(s[1:]); err != nil {
+			return Key{}, err
+		}
+		pairs = append(pairs, [2]string{name, val})
+	}
+	return p.keyFromPairs(pairs)
+}
+
+// cutKeyWord cuts a word written by appendKeyWord from the beginning
+// of s.
+func cutKeyWord(s string) (word, rest string, err error) {
+	if strings.HasPrefix(s, `"`) {
+		q, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return "", "", fmt.Errorf("bad quoted string in key: %s", s)
+		}
+		word, _ = strconv.Unquote(q)
+		return word, s[len(q):], nil
+	}
+	end := strings.IndexAny(s, " :")
+	if end < 0 {
+		end = len(s)
+	}
+	return s[:end], s[end:], nil
+}
+
+// KeyFromJSON decodes a Key encoded by Key.MarshalJSON into p. Field
+// names are handled as in KeyFromText. A JSON null decodes to the
+// zero Key.
+func (p *Projection) KeyFromJSON(data []byte) (Key, error) {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return Key{}, nil
+	}
+	// Decode the object in order, so keys containing duplicate
+	// names decode consistently.
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil {
+		return Key{}, err
+	} else if tok != json.Delim('{') {
+		return Key{}, fmt.Errorf("Key must be a JSON object")
+	}
+	var pairs [][2]string
+	for dec.More() {
+		var name, val string
+		tok, err := dec.Token()
+		if err != nil {
+			return Key{}, err
+		}
+		name = tok.(string)
+		if err := dec.Decode(&val); err != nil {
+			return Key{}, err
+		}
+		pairs = append(pairs, [2]string{name, val})
+	}
+	if _, err := dec.Token(); err != nil {
+		return Key{}, err
+	}
+	return p.keyFromPairs(pairs)
+}
+
+func (p *Projection) keyFromPairs(pairs [][2]string) (Key, error) {
+	fields := make(map[string]*Field)
+	var group *Field
+	for _, f := range p.FlattenedFields() {
+		fields[f.Name] = f
+	}
+	for _, f := range p.Fields() {
+		if f.IsTuple {
+			group = f
+		}
+	}
+
+	for i := range p.row {
+		p.row[i] = ""
+	}
+	for _, pair := range pairs {
+		f := fields[pair[0]]
+		if f == nil {
+			if group == nil {
+				return Key{}, fmt.Errorf("unknown field %q", pair[0])
+			}
+			f = p.addField(group, pair[0])
+			if group.initSub != nil {
+				group.initSub(f)
+			}
+			fields[f.Name] = f
+		}
+		p.row[f.idx] = p.intern([]byte(pair[1]))
+	}
+	return p.internRow(), nil
+}
+
+// projectionJSON is the JSON encoding of a Projection.
+type projectionJSON struct {
+	Fields []*fieldJSON `json:"fields"`
+}
+
+// fieldJSON is the JSON encoding of a Field.
+type fieldJSON struct {
+	Name  string `json:"name"`
+	Tuple bool   `json:"tuple,omitempty"`
+	// Unit indicates this is the Projection's ".unit" field.
+	Unit bool         `json:"unit,omitempty"`
+	Sub  []*fieldJSON `json:"sub,omitempty"`
+
+	// Order is the name of this field's sort order, or of the
+	// sub-fields' sort order for a tuple field. Args are the
+	// arguments of a named order or the values of a "fixed" order.
+	Order string   `json:"order"`
+	Args  []string `json:"args,omitempty"`
+
+	// Observed is the values of a "first" order field in
+	// observation order.
+	Observed []string `json:"observed,omitempty"`
+}
+
+// MarshalJSON encodes the field layout and sort orders of p. This
+// includes the fields added to tuple fields such as ".config" and the
+// observation order of fields that are ordered by first observation.
+// ProjectionParser.UnmarshalProjection decodes the result.
+func (p *Projection) MarshalJSON() ([]byte, error) {
+	var walk func(f *Field) *fieldJSON
+	walk = func(f *Field) *fieldJSON {
+		fj := &fieldJSON{Name: f.Name, Tuple: f.IsTuple, Unit: f == p.unitField, Order: f.spec.name, Args: f.spec.args}
+		for _, sub := range f.Sub {
+			fj.Sub = append(fj.Sub, walk(sub))
+		}
+		if len(f.order) > 0 {
+			fj.Observed = make([]string, len(f.order))
+			for val, i := range f.order {
+				fj.Observed[i] = val
+			}
+		}
+		return fj
+	}
+	var pj projectionJSON
+	for _, f := range p.Fields() {
+		pj.Fields = append(pj.Fields, walk(f))
+	}
+	return json.Marshal(pj)
+}
+
+// UnmarshalProjection reconstructs a Projection encoded by
+// Projection.MarshalJSON. Sort orders are resolved using p, so custom
+// orders must be registered with p before calling UnmarshalProjection.
+//
+// The returned Projection has the same fields and sort order as the
+// encoded Projection, and can decode Keys encoded from it using
+// KeyFromText or KeyFromJSON. However, it doesn't know how to extract
+// fields from a benchfmt.Result, so its Project and ProjectValues
+// methods return Keys with no values.
+func (p *ProjectionParser) UnmarshalProjection(data []byte) (*Projection, error) {
+	var pj projectionJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return nil, err
+	}
+	proj := newProjection()
+	var walk func(parent *Field, fj *fieldJSON) error
+	walk = func(parent *Field, fj *fieldJSON) error {
+		initField, err := p.makeOrder(orderSpec{name: fj.Order, args: fj.Args})
+		if err != nil {
+			return fmt.Errorf("field %s: %w", fj.Name, err)
+		}
+		if fj.Tuple {
+			group := proj.addGroup(parent, fj.Name)
+			group.spec = orderSpec{name: fj.Order, args: fj.Args}
+			group.initSub = initField
+			for _, sub := range fj.Sub {
+				if err := walk(group, sub); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		field := proj.addField(parent, fj.Name)
+		initField(field)
+		if fj.Unit {
+			proj.unitField = field
+		}
+		if field.order != nil {
+			for i, val := range fj.Observed {
+				field.order[val] = i
+			}
+		}
+		return nil
+	}
+	for _, fj := range pj.Fields {
+		if err := walk(proj.root, fj); err != nil {
+			return nil, err
+		}
+	}
+	return proj, nil
+}
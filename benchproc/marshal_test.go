@@ -0,0 +1,179 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"golang.org/x/perf/benchfmt"
+)
+
+func TestKeyMarshal(t *testing.T) {
+	s, _ := mustParse(t, ".name,/size@num,.config")
+	keys := []Key{
+		p(t, s, "Copy/size=4k", "goos", "linux", "cpu", "Intel(R) Xeon: 2GHz"),
+		p(t, s, "Copy/size=1k", "goos", "darwin"),
+		p(t, s, "Move", "quote", `a"b`),
+	}
+
+	wantText := []string{
+		`.name:Copy /size:4k goos:linux cpu:"Intel(R) Xeon: 2GHz"`,
+		`.name:Copy /size:1k goos:darwin`,
+		`.name:Move quote:"a\"b"`,
+	}
+	wantJSON := []string{
+		`{".name":"Copy","/size":"4k","goos":"linux","cpu":"Intel(R) Xeon: 2GHz"}`,
+		`{".name":"Copy","/size":"1k","goos":"darwin"}`,
+		`{".name":"Move","quote":"a\"b"}`,
+	}
+	for i, key := range keys {
+		text, err := key.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(text) != wantText[i] {
+			t.Errorf("MarshalText: got %s, want %s", text, wantText[i])
+		}
+		js, err := json.Marshal(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(js) != wantJSON[i] {
+			t.Errorf("MarshalJSON: got %s, want %s", js, wantJSON[i])
+		}
+
+		// Decoding into the same Projection returns the same Key.
+		if k2, err := s.KeyFromText(text); err != nil {
+			t.Errorf("KeyFromText(%s): %v", text, err)
+		} else if k2 != key {
+			t.Errorf("KeyFromText(%s): got %s, want %s", text, k2, key)
+		}
+		if k2, err := s.KeyFromJSON(js); err != nil {
+			t.Errorf("KeyFromJSON(%s): %v", js, err)
+		} else if k2 != key {
+			t.Errorf("KeyFromJSON(%s): got %s, want %s", js, k2, key)
+		}
+	}
+
+	if _, err := (Key{}).MarshalText(); err == nil {
+		t.Errorf("MarshalText of zero Key: want error")
+	}
+	if js, _ := json.Marshal(Key{}); string(js) != "null" {
+		t.Errorf("MarshalJSON of zero Key: got %s, want null", js)
+	}
+
+	// Unknown fields go into .config if there is one.
+	s2, _ := mustParse(t, ".name")
+	if _, err := s2.KeyFromText([]byte("goos:linux")); err == nil {
+		t.Errorf("KeyFromText with unknown field: want error")
+	}
+	k, err := s.KeyFromText([]byte(".name:Copy goarch:amd64"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := k.String(); got != ".name:Copy goarch:amd64" {
+		t.Errorf("got %s, want .name:Copy goarch:amd64", got)
+	}
+}
+
+func TestProjectionMarshal(t *testing.T) {
+	var pp ProjectionParser
+	f, _ := NewFilter("*")
+	rows, err := pp.Parse(".name,/size@num,.config", f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols, unit, err := pp.ParseWithUnit("note@(new old)", f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Project some results to populate .config and observation
+	// orders.
+	var rowKeys, colKeys []Key
+	for _, r := range []*benchfmt.Result{
+		r(t, "Move/size=1k", "goos", "linux", "note", "old"),
+		r(t, "Copy/size=4k", "goos", "linux", "note", "new"),
+		r(t, "Copy/size=1k", "goos", "darwin", "note", "new"),
+	} {
+		r.Values = []benchfmt.Value{{Value: 1, Unit: "sec/op"}, {Value: 2, Unit: "B/op"}}
+		rowKeys = append(rowKeys, rows.Project(r))
+		colKeys = append(colKeys, cols.ProjectValues(r)...)
+	}
+
+	// Round-trip the projections and keys.
+	roundTrip := func(proj *Projection, keys []Key) (*Projection, []Key) {
+		t.Helper()
+		js, err := json.Marshal(proj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proj2, err := new(ProjectionParser).UnmarshalProjection(js)
+		if err != nil {
+			t.Fatalf("UnmarshalProjection(%s): %v", js, err)
+		}
+		if got, want := fieldNames(proj2.FlattenedFields()), fieldNames(proj.FlattenedFields()); got != want {
+			t.Errorf("got fields %s, want %s", got, want)
+		}
+		// Decode in a different order from how they were
+		// observed to check that observation order is restored.
+		var keys2 []Key
+		for i := len(keys) - 1; i >= 0; i-- {
+			js, err := json.Marshal(keys[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			k, err := proj2.KeyFromJSON(js)
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys2 = append(keys2, k)
+		}
+		return proj2, keys2
+	}
+	sortedStrings := func(keys []Key) string {
+		keys = append([]Key(nil), keys...)
+		SortKeys(keys)
+		var s string
+		for _, k := range keys {
+			s += "[" + k.String() + "]"
+		}
+		return s
+	}
+
+	_, rowKeys2 := roundTrip(rows, rowKeys)
+	if got, want := sortedStrings(rowKeys2), sortedStrings(rowKeys); got != want {
+		t.Errorf("rows: got order %s, want %s", got, want)
+	}
+	cols2, colKeys2 := roundTrip(cols, colKeys)
+	if got, want := sortedStrings(colKeys2), sortedStrings(colKeys); got != want {
+		t.Errorf("cols: got order %s, want %s", got, want)
+	}
+	var unit2 *Field
+	for _, f := range cols2.Fields() {
+		if f.Name == unit.Name {
+			unit2 = f
+		}
+	}
+	if unit2 == nil || cols2.unitField != unit2 {
+		t.Errorf("unit field not restored")
+	}
+
+	// Unknown orders fail to decode.
+	var pp2 ProjectionParser
+	pp2.RegisterOrder("custom", func(a, b string) int { return 0 })
+	custom, err := pp2.Parse("a@custom", f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, _ := json.Marshal(custom)
+	if _, err := new(ProjectionParser).UnmarshalProjection(js); err == nil {
+		t.Errorf("UnmarshalProjection with unregistered order: want error")
+	}
+	if _, err := pp2.UnmarshalProjection(js); err != nil {
+		t.Errorf("UnmarshalProjection with registered order: %v", err)
+	}
+}
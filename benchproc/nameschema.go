@@ -0,0 +1,228 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"golang.org/x/perf/benchfmt"
+	"golang.org/x/perf/benchproc/internal/parse"
+)
+
+// A NameSchema defines sub-name keys for parts of benchmark names
+// that aren't in "key=value" form, such as the "1024" and "gzip" in
+// "BenchmarkDecode/1024/gzip". Given the schema "/size/codec",
+// filters, projections, and derivations constructed with the schema
+// can use "/size" and "/codec" like any other sub-name key, such as
+// the "/size" of "BenchmarkDecode/size=1024". The schema doesn't
+// change the names of benchmark results.
+type NameSchema struct {
+	// keys is the key of each positional sub-name part, or "" to
+	// skip a part. If re is non-nil, keys is unused.
+	keys []string
+
+	// re matches the benchmark name. Each named group is a key.
+	re *regexp.Regexp
+}
+
+// NewNameSchema constructs a NameSchema from a schema description,
+// such as "/size/codec". See "go doc golang.org/x/perf/benchproc/syntax"
+// for a description of name schema syntax.
+func NewNameSchema(schema string) (*NameSchema, error) {
+	errorf := func(off int, msg string) error {
+		return &parse.SyntaxError{Query: schema, Off: off, Msg: msg}
+	}
+
+	if strings.HasPrefix(schema, "^") {
+		re, err := regexp.Compile(schema)
+		if err != nil {
+			return nil, errorf(0, err.Error())
+		}
+		named := false
+		for _, name := range re.SubexpNames() {
+			if name != "" {
+				named = true
+				break
+			}
+		}
+		if !named {
+			return nil, errorf(0, "regexp has no named groups")
+		}
+		return &NameSchema{re: re}, nil
+	}
+
+	if !strings.HasPrefix(schema, "/") {
+		return nil, errorf(0, `name schema must begin with "/" or "^"`)
+	}
+	keys := strings.Split(schema[1:], "/")
+	off := 1
+	for _, key := range keys {
+		if strings.ContainsAny(key, "= \t") {
+			return nil, errorf(off, "bad key "+`"`+key+`"`)
+		}
+		if key == "gomaxprocs" {
+			return nil, errorf(off, "gomaxprocs is not a positional key")
+		}
+		off += len(key) + 1
+	}
+	return &NameSchema{keys: keys}, nil
+}
+
+// NewFilter is like the package-level NewFilter, but the query may
+// also use the sub-name keys defined by s. s may be nil.
+func (s *NameSchema) NewFilter(query string) (*Filter, error) {
+	return newFilter(query, s)
+}
+
+// NewDerivation is like the package-level NewDerivation, but the
+// definition may also use the sub-name keys defined by s. s may be nil.
+func (s *NameSchema) NewDerivation(def string) (*Derivation, error) {
+	return newDerivation(def, s)
+}
+
+// extractor returns an extractor for sub-name key, such as "/size", if
+// s defines it, or nil otherwise. A "key=value" part in the name takes
+// precedence over the schema.
+func (s *NameSchema) extractor(key string) extractor {
+	if s == nil || !strings.HasPrefix(key, "/") || key == "/" || key == "/gomaxprocs" {
+		return nil
+	}
+	name := key[1:]
+	prefix := []byte(key + "=")
+
+	if s.re != nil {
+		i := s.re.SubexpIndex(name)
+		if i < 0 {
+			return nil
+		}
+		return func(res *benchfmt.Result) []byte {
+			if val := extractNamePart(res, prefix, false); val != nil {
+				return val
+			}
+			m, full := s.match(res.Name)
+			if m == nil || m[2*i] < 0 {
+				return nil
+			}
+			return full[m[2*i]:m[2*i+1]]
+		}
+	}
+
+	pos := -1
+	for i, k := range s.keys {
+		if k == name {
+			pos = i
+			break
+		}
+	}
+	if pos < 0 {
+		return nil
+	}
+	return func(res *benchfmt.Result) []byte {
+		if val := extractNamePart(res, prefix, false); val != nil {
+			return val
+		}
+		if part := s.positionalPart(res.Name, pos); part != nil && bytes.IndexByte(part, '=') < 0 {
+			return part[1:]
+		}
+		return nil
+	}
+}
+
+// positionalPart returns the "/"-prefixed sub-name part of full at
+// position pos, or nil if there is none.
+func (s *NameSchema) positionalPart(full benchfmt.Name, pos int) []byte {
+	_, parts := full.Parts()
+	for _, part := range parts {
+		if part[0] != '/' {
+			// GOMAXPROCS suffix.
+			continue
+		}
+		if pos == 0 {
+			return part
+		}
+		pos--
+	}
+	return nil
+}
+
+// match matches s.re against full, excluding any GOMAXPROCS suffix. It
+// returns the submatch indexes, or nil if there is no match, and the
+// name they index.
+func (s *NameSchema) match(full benchfmt.Name) ([]int, []byte) {
+	name := []byte(full)
+	if _, parts := full.Parts(); len(parts) > 0 && parts[len(parts)-1][0] != '/' {
+		name = name[:len(name)-len(parts[len(parts)-1])]
+	}
+	return s.re.FindSubmatchIndex(name), name
+}
+
+// deleteKeys returns full with the parts that s assigns to any of keys
+// (such as "/size") deleted, or nil if there are none. For a regexp
+// schema, this deletes the text matched by each group, along with its
+// "/" separator if the group matched a whole part.
+func (s *NameSchema) deleteKeys(full benchfmt.Name, keys []string) benchfmt.Name {
+	if s == nil {
+		return nil
+	}
+	del := make(map[string]bool)
+	for _, k := range keys {
+		if strings.HasPrefix(k, "/") {
+			del[k[1:]] = true
+		}
+	}
+
+	var out []byte
+	if s.re == nil {
+		base, parts := full.Parts()
+		pos := 0
+		for i, part := range parts {
+			if part[0] != '/' {
+				if out != nil {
+					out = append(out, part...)
+				}
+				continue
+			}
+			if pos < len(s.keys) && del[s.keys[pos]] && bytes.IndexByte(part, '=') < 0 {
+				if out == nil {
+					out = append(out, base...)
+					for _, prev := range parts[:i] {
+						out = append(out, prev...)
+					}
+				}
+			} else if out != nil {
+				out = append(out, part...)
+			}
+			pos++
+		}
+		return out
+	}
+
+	m, name := s.match(full)
+	if m == nil {
+		return nil
+	}
+	last := 0
+	for i, key := range s.re.SubexpNames() {
+		start, end := m[2*i], m[2*i+1]
+		// Skip groups that don't match, aren't deleted, or are
+		// nested in a deleted group.
+		if !del[key] || start < 0 || start < last || end == start {
+			continue
+		}
+		if start > 0 && name[start-1] == '/' && (end == len(name) || name[end] == '/') {
+			// Delete the separator of a whole part.
+			start--
+		}
+		out = append(out, name[last:start]...)
+		last = end
+	}
+	if last == 0 {
+		return nil
+	}
+	out = append(out, name[last:]...)
+	return append(out, full[len(name):]...)
+}
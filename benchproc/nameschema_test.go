@@ -0,0 +1,149 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"testing"
+
+	"golang.org/x/perf/benchfmt"
+)
+
+func TestNameSchema(t *testing.T) {
+	// check checks the values of keys extracted from name. A want of
+	// "<nil>" means the key is missing.
+	check := func(t *testing.T, schema, name string, keyVals ...string) {
+		t.Helper()
+		s, err := NewNameSchema(schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := &benchfmt.Result{Name: benchfmt.Name(name)}
+		for i := 0; i < len(keyVals); i += 2 {
+			key, want := keyVals[i], keyVals[i+1]
+			ext, err := newExtractor(key, s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := "<nil>"
+			if val := ext(res); val != nil {
+				got = string(val)
+			}
+			if got != want {
+				t.Errorf("%s: %s: %s is %s, want %s", schema, name, key, got, want)
+			}
+		}
+		if res.Name.String() != name {
+			t.Errorf("%s: %s: name changed to %s", schema, name, res.Name)
+		}
+	}
+
+	t.Run("positional", func(t *testing.T) {
+		check(t, "/size/codec", "Decode/1024/gzip", "/size", "1024", "/codec", "gzip")
+		check(t, "/size/codec", "Decode/1024/gzip-8", "/size", "1024", "/codec", "gzip", "/gomaxprocs", "8")
+		check(t, "/size/codec", "Decode/1024", "/size", "1024", "/codec", "<nil>")
+		check(t, "/size/codec", "Decode/1024/gzip/extra", "/codec", "gzip", "/extra", "<nil>")
+		check(t, "/size/codec", "Decode-8", "/size", "<nil>")
+		check(t, "//codec", "Decode/1024/gzip", "/codec", "gzip", "/", "<nil>")
+		// Existing key=value parts take precedence and take a position.
+		check(t, "/size/codec", "Decode/size=1k/gzip", "/size", "1k", "/codec", "gzip")
+		check(t, "/size/codec", "Decode/a=b/c=d", "/size", "<nil>", "/codec", "<nil>", "/a", "b")
+		check(t, "/size/codec", "Decode/gzip/size=1k", "/size", "1k", "/codec", "<nil>")
+	})
+
+	t.Run("regexp", func(t *testing.T) {
+		check(t, `^Decode/(?P<size>[0-9]+)/(?P<codec>\w+)$`, "Decode/1024/gzip-8", "/size", "1024", "/codec", "gzip")
+		check(t, `^Decode/(?P<size>[0-9]+)/(?P<codec>\w+)$`, "Encode/1024/gzip", "/size", "<nil>")
+		check(t, `^Decode(?P<codec>[A-Z]\w*)`, "DecodeGzip/size=1k", "/codec", "Gzip", "/size", "1k")
+		check(t, `^(\w+)/(?P<size>\d+)(?P<unit>[kM]?)B$`, "Copy/4kB", "/size", "4", "/unit", "k")
+		check(t, `^(?P<op>Encode|Decode)/(?P<size>\d+)?`, "Decode/x", "/op", "Decode", "/size", "<nil>")
+	})
+
+	t.Run("fullname", func(t *testing.T) {
+		for _, test := range []struct {
+			schema, name string
+			exclude      []string
+			want         string
+		}{
+			{"/size/codec", "Decode/1024/gzip-8", []string{"/codec"}, "Decode/1024-8"},
+			{"/size/codec", "Decode/1024/gzip-8", []string{"/size", "/gomaxprocs"}, "Decode/gzip"},
+			{"/size/codec", "Decode/1024/gzip/level=9", []string{"/codec", "/level"}, "Decode/1024"},
+			{"/size/codec", "Decode/size=1k/gzip", []string{"/size"}, "Decode/gzip"},
+			{`^Decode(?P<codec>[A-Z]\w*)/(?P<size>\d+)`, "DecodeGzip/1024-8", []string{"/codec"}, "Decode/1024-8"},
+			{`^Decode(?P<codec>[A-Z]\w*)/(?P<size>\d+)`, "DecodeGzip/1024-8", []string{"/size"}, "DecodeGzip-8"},
+			{`^Decode(?P<codec>[A-Z]\w*)/(?P<size>\d+)`, "Encode/1024", []string{"/size"}, "Encode/1024"},
+		} {
+			s, err := NewNameSchema(test.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := &benchfmt.Result{Name: benchfmt.Name(test.name)}
+			if got := string(newExtractorFullName(test.exclude, s)(res)); got != test.want {
+				t.Errorf("%s: %s excluding %v: got %s, want %s", test.schema, test.name, test.exclude, got, test.want)
+			}
+		}
+	})
+
+	t.Run("users", func(t *testing.T) {
+		s, err := NewNameSchema("/size/codec")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := &benchfmt.Result{Name: benchfmt.Name("Decode/1024/gzip-8"), Iters: 1, Values: []benchfmt.Value{{Value: 2048, Unit: "sec/op"}}}
+
+		f, err := s.NewFilter("/size>=1k /codec:gzip /gomaxprocs:8")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, _ := f.Apply(res); !ok {
+			t.Errorf("filter did not match %s", res.Name)
+		}
+		// Without the schema, the keys are missing.
+		f, err = NewFilter("/codec:gzip")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, _ := f.Apply(res); ok {
+			t.Errorf("filter without schema matched %s", res.Name)
+		}
+
+		pp := ProjectionParser{NameSchema: s}
+		row, err := pp.Parse(".fullname", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		col, err := pp.Parse("/codec", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := row.Project(res).String(); got != ".fullname:Decode/1024-8" {
+			t.Errorf("row: got %s", got)
+		}
+		if got := col.Project(res).String(); got != "/codec:gzip" {
+			t.Errorf("col: got %s", got)
+		}
+
+		ds := Derivations{}
+		if err := ds.Set("sec/elem=sec/op / /size"); err != nil {
+			t.Fatal(err)
+		}
+		if ds, err = ds.WithNameSchema(s); err != nil {
+			t.Fatal(err)
+		}
+		// The failed filter dropped the measurements.
+		res.Values = []benchfmt.Value{{Value: 2048, Unit: "sec/op"}}
+		ds.Apply(res)
+		if got, ok := res.Value("sec/elem"); !ok || got != 2 {
+			t.Errorf("derived sec/elem %v, %v, want 2", got, ok)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, schema := range []string{"", "size", "/a=b", "/gomaxprocs", "^(", "^Decode/[0-9]+"} {
+			if _, err := NewNameSchema(schema); err == nil {
+				t.Errorf("%q: want error", schema)
+			}
+		}
+	})
+}
@@ -23,10 +23,19 @@ import (
 
 // A ProjectionParser parses one or more related projection expressions.
 type ProjectionParser struct {
+	// NameSchema, if non-nil, defines additional sub-name keys
+	// that projections may use. It must be set before calling
+	// Parse.
+	NameSchema *NameSchema
+
 	configKeys   map[string]bool // Specific .config keys (excluded from .config)
 	fullnameKeys []string        // Specific sub-name keys (excluded from .fullname)
 	haveConfig   bool            // .config was projected
 	haveFullname bool            // .fullname was projected
+	configGroups []*Field        // .config fields of all projections
+
+	orders map[string]func(a, b string) int // Custom orders
+	gits   map[string]CommitHistory         // Git histories by directory
 
 	// Fields below here are constructed when the first Result is
 	// processed.
@@ -66,6 +75,7 @@ func (p *ProjectionParser) Parse(projection string, filter *Filter) (*Projection
 		return nil, err
 	}
 	var filterParts []filterFn
+	var filterNodes []*filterNode
 	for _, part := range parts {
 		f, err := p.makeProjection(proj, projection, part)
 		if err != nil {
@@ -73,6 +83,7 @@ func (p *ProjectionParser) Parse(projection string, filter *Filter) (*Projection
 		}
 		if f != nil {
 			filterParts = append(filterParts, f)
+			filterNodes = append(filterNodes, &filterNode{expr: part.String(), fn: f})
 		}
 	}
 	// Now that we've ensured the projection is valid, add any
@@ -83,6 +94,12 @@ func (p *ProjectionParser) Parse(projection string, filter *Filter) (*Projection
 		}
 		filterParts = append(filterParts, filter.match)
 		filter.match = filterOp(parse.OpAnd, filterParts)
+		filterNodes = append(filterNodes, filter.tree)
+		exprs := make([]string, len(filterNodes))
+		for i, n := range filterNodes {
+			exprs[i] = n.expr
+		}
+		filter.tree = &filterNode{expr: "(" + strings.Join(exprs, " AND ") + ")", fn: filter.match, subs: filterNodes}
 	}
 
 	return proj, nil
@@ -106,14 +123,111 @@ func (p *ProjectionParser) ParseWithUnit(projection string, filter *Filter) (*Pr
 		return nil, nil, err
 	}
 	field := proj.addField(proj.root, ".unit")
-	field.order = make(map[string]int)
-	field.cmp = func(a, b string) int {
-		return field.order[a] - field.order[b]
-	}
+	initField, _ := p.makeOrder(orderSpec{name: "first"})
+	initField(field)
 	proj.unitField = field
 	return proj, field, nil
 }
 
+// RegisterOrder registers a custom named sort order for use in
+// projections parsed by p after this call, as in "key@name". cmp must
+// return a negative number if a sorts before b, a positive number if
+// a sorts after b, and 0 if they are unordered. A custom order
+// overrides a built-in order with the same name.
+//
+// To order commit hashes by a commit history from some source other
+// than the built-in "git" order, use CommitOrder.
+//
+// RegisterOrder panics if name is empty or is one of the reserved
+// names "first", "fixed", or "git".
+func (p *ProjectionParser) RegisterOrder(name string, cmp func(a, b string) int) {
+	if name == "" || name == "first" || name == "fixed" || name == "git" {
+		panic(fmt.Sprintf("invalid order name %q", name))
+	}
+	if p.orders == nil {
+		p.orders = make(map[string]func(a, b string) int)
+	}
+	p.orders[name] = cmp
+}
+
+// An orderSpec describes the sort order of a Field, so it can be
+// reconstructed when a Projection is decoded.
+type orderSpec struct {
+	// name is "first", "fixed", or a named order.
+	name string
+	// args are the values of a "fixed" order, or the arguments
+	// of a named order.
+	args []string
+}
+
+// makeOrder returns a function that initializes the sort order of a
+// Field according to spec.
+func (p *ProjectionParser) makeOrder(spec orderSpec) (func(field *Field), error) {
+	var cmp func(a, b string) int
+	if spec.name == "fixed" {
+		fixedMap := make(map[string]int, len(spec.args))
+		for i, s := range spec.args {
+			fixedMap[s] = i
+		}
+		cmp = func(a, b string) int {
+			return fixedMap[a] - fixedMap[b]
+		}
+	} else if spec.name == "first" {
+		return func(field *Field) {
+			field.spec = spec
+			field.order = make(map[string]int)
+			field.cmp = func(a, b string) int {
+				return field.order[a] - field.order[b]
+			}
+		}, nil
+	} else if spec.name == "git" {
+		var err error
+		cmp, err = p.gitOrder(spec.args)
+		if err != nil {
+			return nil, err
+		}
+	} else if spec.args != nil {
+		return nil, fmt.Errorf("order %q does not take arguments", spec.name)
+	} else if c, ok := p.orders[spec.name]; ok {
+		cmp = c
+	} else if c, ok := builtinOrders[spec.name]; ok {
+		cmp = c
+	} else {
+		return nil, fmt.Errorf("unknown order %q", spec.name)
+	}
+	return func(field *Field) {
+		field.spec = spec
+		field.cmp = cmp
+	}, nil
+}
+
+// gitOrder returns the comparison function for the "git" order with
+// the given arguments. It reads each repository only once per
+// ProjectionParser.
+func (p *ProjectionParser) gitOrder(args []string) (func(a, b string) int, error) {
+	dir := "."
+	switch len(args) {
+	case 0:
+	case 1:
+		dir = args[0]
+	default:
+		return nil, fmt.Errorf("git order takes at most one repository directory")
+	}
+	h, ok := p.gits[dir]
+	if !ok {
+		var err error
+		h, err = NewGitHistory(dir)
+		if err != nil {
+			return nil, err
+		}
+		if p.gits == nil {
+			p.gits = make(map[string]CommitHistory)
+		}
+		p.gits[dir] = h
+	}
+	return CommitOrder(h), nil
+}
+
 // Residue returns a projection for any field not yet projected by any
 // projection parsed by p. The resulting Projection does not have a
 // meaningful order.
@@ -147,38 +261,32 @@ func (p *ProjectionParser) Residue() *Projection {
 
 func (p *ProjectionParser) makeProjection(s *Projection, q string, proj parse.Field) (filterFn, error) {
 	// Construct the order function.
-	var initField func(field *Field)
+	spec := orderSpec{name: proj.Order, args: proj.OrderArgs}
+	if proj.Order == "fixed" {
+		spec.args = proj.Fixed
+	}
+	initField, err := p.makeOrder(spec)
+	if err != nil {
+		return nil, &parse.SyntaxError{Query: q, Off: proj.OrderOff, Msg: err.Error()}
+	}
 	var filter filterFn
 	makeFilter := func(ext extractor) {}
 	if proj.Order == "fixed" {
-		fixedMap := make(map[string]int, len(proj.Fixed))
-		for i, s := range proj.Fixed {
-			fixedMap[s] = i
-		}
-		initField = func(field *Field) {
-			field.cmp = func(a, b string) int {
-				return fixedMap[a] - fixedMap[b]
-			}
+		fixedMap := make(map[string]bool, len(proj.Fixed))
+		for _, s := range proj.Fixed {
+			fixedMap[s] = true
 		}
 		makeFilter = func(ext extractor) {
 			filter = func(res *benchfmt.Result) (mask, bool) {
-				_, ok := fixedMap[string(ext(res))]
-				return nil, ok
+				return nil, fixedMap[string(ext(res))]
 			}
 		}
-	} else if proj.Order == "first" {
-		initField = func(field *Field) {
-			field.order = make(map[string]int)
-			field.cmp = func(a, b string) int {
-				return field.order[a] - field.order[b]
-			}
-		}
-	} else if cmp, ok := builtinOrders[proj.Order]; ok {
-		initField = func(field *Field) {
-			field.cmp = cmp
-		}
-	} else {
-		return nil, &parse.SyntaxError{Query: q, Off: proj.OrderOff, Msg: fmt.Sprintf("unknown order %q", proj.Order)}
+	}
+
+	if len(proj.Rewrites) > 0 && (proj.Key == ".config" || proj.Key == ".fullname") {
+		// Rewrites don't make sense for a whole tuple, and
+		// .fullname's value depends on other projections.
+		return nil, &parse.SyntaxError{Query: q, Off: proj.Rewrites[0].Off, Msg: "value rewrites not allowed for " + proj.Key}
 	}
 
 	var project func(*benchfmt.Result, *[]string)
@@ -193,6 +301,9 @@ func (p *ProjectionParser) makeProjection(s *Projection, q string, proj parse.Fi
 
 		p.haveConfig = true
 		group := s.addGroup(s.root, ".config")
+		group.spec = spec
+		group.initSub = initField
+		p.configGroups = append(p.configGroups, group)
 		seen := make(map[string]*Field)
 		project = func(r *benchfmt.Result, row *[]string) {
 			for _, cfg := range r.Config {
@@ -235,14 +346,14 @@ func (p *ProjectionParser) makeProjection(s *Projection, q string, proj parse.Fi
 
 		project = func(r *benchfmt.Result, row *[]string) {
 			if p.fullExtractor == nil {
-				p.fullExtractor = newExtractorFullName(p.fullnameKeys)
+				p.fullExtractor = newExtractorFullName(p.fullnameKeys, p.NameSchema)
 			}
 			val := p.fullExtractor(r)
 			(*row)[field.idx] = s.intern(val)
 		}
 
-	case ".unit":
-		return nil, &parse.SyntaxError{Query: q, Off: proj.KeyOff, Msg: ".unit is only allowed in filters"}
+	case ".unit", ".value":
+		return nil, &parse.SyntaxError{Query: q, Off: proj.KeyOff, Msg: proj.Key + " is only allowed in filters"}
 
 	default:
 		// This is a specific sub-name or file key. Add it
@@ -252,10 +363,13 @@ func (p *ProjectionParser) makeProjection(s *Projection, q string, proj parse.Fi
 		} else {
 			p.configKeys[proj.Key] = true
 		}
-		ext, err := newExtractor(proj.Key)
+		ext, err := newExtractor(proj.Key, p.NameSchema)
 		if err != nil {
 			return nil, &parse.SyntaxError{Query: q, Off: proj.KeyOff, Msg: err.Error()}
 		}
+		for _, r := range proj.Rewrites {
+			ext = newRewriteExtractor(ext, r)
+		}
 		field := s.addField(s.root, proj.Key)
 		initField(field)
 		makeFilter(ext)
@@ -414,6 +528,14 @@ type Field struct {
 	// order, if non-nil, records the observation order of this
 	// field.
 	order map[string]int
+
+	// spec describes the sort order of this field, or of the
+	// sub-Fields of a group field.
+	spec orderSpec
+
+	// initSub, if non-nil, initializes new sub-Fields of a group
+	// field.
+	initSub func(field *Field)
 }
 
 // String returns the name of Field f.
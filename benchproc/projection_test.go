@@ -147,6 +147,45 @@ func TestProjectionParsing(t *testing.T) {
 	checkErr("a@foo", "unknown order \"foo\"", 2)
 
 	checkErr(".config@(1 2)", "fixed order not allowed for .config", 8)
+	checkErr(".config@alias(a=b)", "value rewrites not allowed for .config", 8)
+	checkErr(".fullname@re(/a/)", "value rewrites not allowed for .fullname", 10)
+	checkErr(".value", ".value is only allowed in filters", 0)
+}
+
+func TestProjectionRewrite(t *testing.T) {
+	check := func(s *Projection, val string, want string) {
+		t.Helper()
+		got := p(t, s, "Name/a="+val, "a", val).String()
+		if got != want {
+			t.Errorf("%s: got %s, want %s", val, got, want)
+		}
+	}
+
+	s, _ := mustParse(t, "a@alias(abc123=old def456=new)")
+	check(s, "abc123", "a:old")
+	check(s, "def456", "a:new")
+	check(s, "xyz", "a:xyz")
+
+	s, _ = mustParse(t, "/a@re(/(Xeon|EPYC)/ /^Apple/)")
+	check(s, "Intel(R) Xeon(R) CPU", "/a:Xeon")
+	check(s, "AMD EPYC 7B13", "/a:EPYC")
+	check(s, "Apple M1", "/a:Apple")
+	check(s, "Other", "/a:Other")
+
+	// Rewrites apply in order, and before sorting and filtering.
+	s, f := mustParse(t, "a@re(/^(.*)-v[0-9]$/)@alias(x=y)@(z y)")
+	check(s, "x-v2", "a:y")
+	check(s, "z", "a:z")
+	for val, want := range map[string]bool{"x-v1": true, "x": true, "z-v1": true, "w": false} {
+		if got, _ := f.Apply(r(t, "", "a", val)); got != want {
+			t.Errorf("%s: filter got %v, want %v", val, got, want)
+		}
+	}
+	ks := []Key{p(t, s, "", "a", "y"), p(t, s, "", "a", "z")}
+	SortKeys(ks)
+	if ks[0].String() != "a:z" || ks[1].String() != "a:y" {
+		t.Errorf("bad sort order: %v", ks)
+	}
 }
 
 func TestProjectionFiltering(t *testing.T) {
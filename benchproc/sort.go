@@ -10,6 +10,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Less reports whether k comes before o in the sort order implied by
@@ -100,15 +101,80 @@ var builtinOrders = map[string]func(a, b string) int{
 		}
 		return 1
 	},
+	"semver":    compareVersionStrings,
+	"goversion": compareVersionStrings,
+	"time":      compareTimeStrings,
+}
+
+// compareVersionStrings compares a and b as versions. Values that
+// aren't versions sort after values that are.
+func compareVersionStrings(a, b string) int {
+	aa, oka := parseVersion(a)
+	bb, okb := parseVersion(b)
+	switch {
+	case oka && okb:
+		return compareVersions(aa, bb)
+	case oka:
+		return -1
+	case okb:
+		return 1
+	}
+	// The values are unordered.
+	return 0
+}
+
+// compareTimeStrings compares a and b as times. Values that aren't
+// times sort after values that are.
+func compareTimeStrings(a, b string) int {
+	aa, erra := parseTime(a)
+	bb, errb := parseTime(b)
+	switch {
+	case erra == nil && errb == nil:
+		return aa.Compare(bb)
+	case erra == nil:
+		return -1
+	case errb == nil:
+		return 1
+	}
+	// The values are unordered.
+	return 0
+}
+
+var compactTimeRe = regexp.MustCompile(`^[0-9]{8}T[0-9]{6}$`)
+
+// parseTime parses a time in RFC 3339 format, with or without
+// fractional seconds, or in the compact form "20060102T150405",
+// which is taken to be in UTC. These are the forms accepted by
+// benchseries.NormalizeDateString.
+func parseTime(x string) (time.Time, error) {
+	if compactTimeRe.MatchString(x) {
+		return time.Parse("20060102T150405", x)
+	}
+	return time.Parse(time.RFC3339Nano, x)
 }
 
 const numPrefixes = `KMGTPEZY`
 
 var numRe = regexp.MustCompile(`([0-9.]+)([k` + numPrefixes + `]i?)?[bB]?`)
 
+// exactNumRe is numRe, but matching all of a string.
+var exactNumRe = regexp.MustCompile(`^` + numRe.String() + `$`)
+
 // parseNum is a fuzzy number parser. It supports common patterns,
-// such as SI prefixes.
+// such as SI prefixes. It finds the number anywhere in x, so, for
+// example, "go1.21" parses as 1.21.
 func parseNum(x string) (float64, error) {
+	return parseNumRe(x, numRe)
+}
+
+// parseExactNum is like parseNum, but x must consist of just the
+// number and its suffixes. It's used for comparisons, where "go1.21"
+// or "1kx" shouldn't be numbers.
+func parseExactNum(x string) (float64, error) {
+	return parseNumRe(x, exactNumRe)
+}
+
+func parseNumRe(x string, numRe *regexp.Regexp) (float64, error) {
 	// Try parsing as a regular float.
 	v, err := strconv.ParseFloat(x, 64)
 	if err == nil {
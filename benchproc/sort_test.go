@@ -7,6 +7,7 @@ package benchproc
 import (
 	"math/rand"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -101,6 +102,47 @@ func TestSort(t *testing.T) {
 		p(t, s, "", "a", "c"),
 	}
 	check(k, "a:c", "a:b", "a:a")
+
+	// Versions.
+	for _, order := range []string{"semver", "goversion"} {
+		s, _ = mustParse(t, "a@"+order)
+		k = []Key{
+			p(t, s, "", "a", "go1.21.3"),
+			p(t, s, "", "a", "xyz"),
+			p(t, s, "", "a", "devel go1.23-abc"),
+			p(t, s, "", "a", "go1.9"),
+			p(t, s, "", "a", "go1.21rc2"),
+			p(t, s, "", "a", "go1.22"),
+		}
+		check(k, "a:go1.9", "a:go1.21rc2", "a:go1.21.3", "a:go1.22", "a:devel go1.23-abc", "a:xyz")
+	}
+
+	// Times.
+	s, _ = mustParse(t, "a@time")
+	k = []Key{
+		p(t, s, "", "a", "2021-12-29T21:32:12Z"),
+		p(t, s, "", "a", "yesterday"),
+		p(t, s, "", "a", "20211229T213211"),
+		p(t, s, "", "a", "2021-12-29T21:32:12.5+00:00"),
+		p(t, s, "", "a", "2021-12-29T16:32:13-05:00"),
+	}
+	check(k, "a:20211229T213211", "a:2021-12-29T21:32:12Z", "a:2021-12-29T21:32:12.5+00:00", "a:2021-12-29T16:32:13-05:00", "a:yesterday")
+
+	// Custom.
+	var pp ProjectionParser
+	pp.RegisterOrder("len", func(a, b string) int { return len(a) - len(b) })
+	pp.RegisterOrder("alpha", func(a, b string) int { return -strings.Compare(a, b) })
+	s, err := pp.Parse("a@len,b@alpha", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k = []Key{
+		p(t, s, "", "a", "ccc", "b", "x"),
+		p(t, s, "", "a", "a", "b", "x"),
+		p(t, s, "", "a", "bb", "b", "x"),
+		p(t, s, "", "a", "bb", "b", "y"),
+	}
+	check(k, "a:a b:x", "a:bb b:y", "a:bb b:x", "a:ccc b:x")
 }
 
 func TestParseNum(t *testing.T) {
@@ -130,4 +172,23 @@ func TestParseNum(t *testing.T) {
 	check("1E", 1000000000000000000)
 	check("1Z", 1000000000000000000000)
 	check("1Y", 1000000000000000000000000)
+
+	// parseNum finds the number anywhere in x, as it always has.
+	check("1kx", 1000)
+	check("go1.21", 1.21)
+	check("size=1k", 1000)
+
+	for _, x := range []string{"", "a"} {
+		if got, err := parseNum(x); err == nil {
+			t.Errorf("%s: want error, got %v", x, got)
+		}
+	}
+	for _, x := range []string{"", "a", "go1.21", "1kx", "size=1k"} {
+		if got, err := parseExactNum(x); err == nil {
+			t.Errorf("%s: parseExactNum: want error, got %v", x, got)
+		}
+	}
+	if got, err := parseExactNum("4KiB"); err != nil || got != 4096 {
+		t.Errorf("4KiB: parseExactNum: want 4096, got %v, %v", got, err)
+	}
 }
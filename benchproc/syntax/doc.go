@@ -51,6 +51,23 @@
 // both original units (e.g., "ns/op") and tidied units (e.g.,
 // "sec/op").
 //
+// - ".value" (only in filters) refers to the value of individual
+// measurements in a result. Like ".unit", it selects individual
+// measurements, so ".value>1ms" extracts the time measurements of
+// results that took more than 1ms per op. Values are compared in
+// tidied units (for example, "ns/op" measurements are in "sec/op"), so
+// inputs in ns/op and sec/op compare alike. A value to compare with is
+// a number, optionally with an SI or IEC suffix such as "4k", which
+// is compared with measurements in any unit, or a quantity with a
+// unit, such as "1ms", "4KiB", or "10MB/s", which is compared only
+// with measurements in that unit. A unit without a denominator, like
+// "ms", also matches that unit per anything, like "sec/op". In a
+// "key:value" term, value may also be a regexp, which is matched
+// against the tidied value formatted as a Go float, and "-.value:0"
+// drops measurements of 0.
+//
+// - ".iters" refers to the iteration count of a result.
+//
 // - ".file" refers to the input file provided on the command line
 // (for command-line tools that use benchfmt.Files).
 //
@@ -65,10 +82,19 @@
 //	key:"value"   - Same, but value is a double-quoted Go string that
 //	                may contain spaces or other special characters.
 //	"key":value   - Keys may also be double-quoted.
+//	key:glob*     - Match if key's value matches a glob pattern. In a
+//	                bare (unquoted) value, "*" matches any sequence of
+//	                characters (including "/"), and "?" matches any
+//	                single character. A glob must match the whole value.
 //	key:/regexp/  - Match if key's value matches a regular expression.
+//	key:/regexp/i - Same, but match case-insensitively.
 //	key:(val1 OR val2 OR ...)
 //	              - Short-hand for key:val1 OR key:val2. Values may be
-//	                double-quoted strings or regexps.
+//	                double-quoted strings, globs, or regexps.
+//	key<value     - Match if key's value is less than value. Likewise,
+//	key<=value      "<=", ">", and ">=" match if key's value is less
+//	key>value       than or equal to, greater than, or greater than or
+//	key>=value      equal to value.
 //	*             - Match everything.
 //
 // These terms can be combined into larger expressions as follows:
@@ -79,6 +105,22 @@
 //	-x            - Match if x does not match.
 //	(...)         - Subexpression.
 //
+// Comparisons are by version if both key's value and value are
+// versions such as "1.22", "v1.2.3", "go1.21rc1", or "devel
+// go1.23-abcdef" (for example, "goversion>=go1.21"). This includes
+// any dot-separated sequence of integers, so "1.10" is greater than
+// "1.9". Otherwise, they are numeric if both are numbers, which may
+// use metric and IEC prefixes like the "num" sort order (for example,
+// "/size>=4k"). If neither applies, the comparison does not match.
+// ".unit" and ".config" cannot be compared. Negative values must be
+// quoted, as in /delta>"-1".
+//
+// "<" and ">" are only operators immediately after a key, so a value
+// may contain them, as in "/name:a<b".
+//
+// Values in double quotes are always matched literally, so
+// key:"a*b" matches only the value "a*b".
+//
 // Precise syntax:
 //
 //	expr     = andExpr {"OR" andExpr}
@@ -88,9 +130,13 @@
 //	         | "*"
 //	         | key ":" value
 //	         | key ":" "(" value {"OR" value} ")"
-//	key      = word
+//	         | key ("<" | "<=" | ">" | ">=") word
+//	key      = [^-*"():@,<>][^ ():@,<>]*
+//	         | double-quoted Go string
 //	value    = word
-//	         | "/" regexp "/"
+//	         | glob
+//	         | "/" regexp "/" ["i"]
+//	glob     = [^-"():@, ][^ ():@,]* (containing "*" or "?")
 //
 // # Projections
 //
@@ -104,23 +150,133 @@
 // - "key" extracts the named field and orders it using the order
 // values of this key are first observed in the data.
 //
-// - "key@order" specifies one of the built-in named sort orders. This
-// can be "alpha" or "num" for alphabetic or numeric sorting. "num"
-// understands basic use of metric and IEC prefixes like "2k" and
-// "1Mi".
+// - "key@order" specifies one of the built-in named sort orders:
+//
+//	alpha     - Alphabetic order.
+//	num       - Numeric order. This understands basic use of metric
+//	            and IEC prefixes like "2k" and "1Mi".
+//	semver    - Version order, for values like "v1.2.3", "1.2.3-rc.1",
+//	            "go1.21.3", "go1.22rc1", or "devel go1.23-abcdef". A
+//	            pre-release sorts before its release, and a development
+//	            version sorts before the release it's named for.
+//	goversion - Same as semver.
+//	time      - Time order, for RFC 3339 times like
+//	            "2021-12-29T21:32:12Z" or compact UTC times like
+//	            "20211229T213212".
+//
+// - "key@git(dir)" orders commit hashes by their topological position
+// in the history of the git repository in directory dir, so a commit
+// sorts after its parents. If dir is omitted, as in "key@git", it uses
+// the repository in the current directory. Values may be abbreviated
+// hashes. This requires the git command.
+//
+// For all orders except alpha, values that can't be parsed (or
+// commits that aren't in the repository) sort after those that can.
+// Applications may provide additional named orders.
 //
 // - "key@(value value ...)" specifies a fixed value order for key.
 // It also specifies a filter: if key has a value that isn't any of
 // the specified values, the result is filtered out.
 //
+// A field may also rewrite the values of its key before they are
+// grouped, ordered, or filtered. This is useful for mapping values
+// that differ only cosmetically, or that are hard to read, to
+// canonical labels. Rewrites are written between the key and the
+// sort order and are applied in order. Values that don't match a
+// rewrite are left unchanged.
+//
+// - "key@alias(value=alias value=alias ...)" replaces each listed
+// value with its alias. For example,
+// "toolchain@alias(abc123=old def456=new)" labels two commit hashes
+// "old" and "new". If the value contains spaces or other special
+// characters, double-quote the whole "value=alias" pair. The value is
+// split from the alias at the last "=".
+//
+// - "key@re(/regexp/ /regexp/ ...)" replaces each value with the text
+// matched by the first regexp that matches it. If that regexp has a
+// capturing group, the value is replaced with the text matched by the
+// first group. For example, "cpu@re(/(Xeon|EPYC)/)" groups results by
+// CPU family.
+//
+// Rewrites are not allowed for ".config" or ".fullname".
+//
 // Precise syntax:
 //
 //	expr     = part {","? part}
-//	part     = key
-//	         | key "@" order
-//	         | key "@" "(" word {word} ")"
+//	part     = key {"@" rewrite} ["@" order]
+//	order    = word ["(" {word} ")"]
+//	         | "(" word {word} ")"
+//	rewrite  = "alias" "(" word {word} ")"
+//	         | "re" "(" "/" regexp "/" ["i"] {"/" regexp "/" ["i"]} ")"
 //	key      = word
-//	order    = word
+//
+// # Derived units
+//
+// A derived unit definition computes a new measurement for each
+// benchmark result from its existing measurements and keys. For
+// example, the following computes the time per element of a benchmark
+// with a "/size" sub-name key, and the bytes allocated per allocation:
+//
+//	ns/elem=sec/op / /size
+//	B/alloc=B/op / allocs/op
+//
+// A definition consists of the derived unit, "=", and an arithmetic
+// expression using "+", "-", "*", "/", and parentheses, with the usual
+// precedence. Because units contain characters like "/", operators must
+// be separated from operands by spaces. Each operand is one of:
+//
+// - A number, such as "2" or "1e-3".
+//
+// - A "/"- or "."-prefixed key, such as "/size". The key's value is
+// parsed like the "num" sort order, so it may use metric and IEC
+// prefixes like "4k" and "1Mi".
+//
+// - A unit, such as "ns/op". If the result has no measurement in this
+// unit, it is instead treated as a file configuration key.
+//
+// Measurements are always taken in tidied units, so "ns/op", "ms/op",
+// and "sec/op" all refer to the time in seconds, and the derived value is
+// taken to be in the tidied form of the derived unit. Hence, the first
+// example above computes seconds per element and reports it in the
+// "ns/elem" unit. If any operand is missing or the derived value is not
+// finite, the measurement is omitted from that result.
+//
+// Precise syntax:
+//
+//	def      = unit "=" expr
+//	expr     = term {("+" | "-") term}
+//	term     = factor {("*" | "/") factor}
+//	factor   = "(" expr ")"
+//	         | number
+//	         | key
+//	         | unit
+//
+// # Name schemas
+//
+// A name schema defines keys for sub-benchmark name parts that aren't
+// in "key=value" form, so they can be used in filters, projections,
+// and derived units like any other "/{key}". For example, given the
+// schema "/size/codec", the benchmark "BenchmarkDecode/1024/gzip-8"
+// has "/size" "1024" and "/codec" "gzip", just like
+// "BenchmarkDecode/size=1024/codec=gzip-8". The schema doesn't change
+// the benchmark name, so ".name" and ".fullname" are unaffected, except
+// that, like other keys, schema keys used in a projection are excluded
+// from ".fullname" in the other projections.
+//
+// A positional schema is a sequence of "/"-prefixed keys, one for each
+// "/"-separated part of the name after the base name. An empty key,
+// as in "//codec", skips that part. Parts that are already in
+// "key=value" form take a position but don't define the positional
+// key. A "key=value" part in the name always takes precedence over a
+// schema key of the same name.
+//
+// A schema that begins with "^" is a regular expression matched against
+// the benchmark name, excluding the "Benchmark" prefix and the
+// GOMAXPROCS suffix. Each named capturing group is a key whose value is
+// the text it matches. For example, with "^Decode(?P<codec>[A-Z]\w*)",
+// "BenchmarkDecodeGzip" has "/codec" "Gzip". If the regular expression
+// doesn't match, or a group doesn't participate in the match, the key
+// is missing.
 //
 // # Common syntax
 //
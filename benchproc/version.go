@@ -0,0 +1,126 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import (
+	"strconv"
+	"strings"
+)
+
+// A version is a parsed version string, such as "1.2.3", "v1.2.3",
+// "go1.21rc1", or "devel go1.23-abcdef".
+type version struct {
+	nums []int
+	// pre is the pre-release rank: 0 for a release, and negative
+	// for pre-releases, where lower values sort earlier.
+	pre    int
+	preNum int
+}
+
+// Pre-release ranks.
+const (
+	preDevel = -4 + iota
+	preAlpha
+	preBeta
+	preRC
+)
+
+// parseVersion parses a version string. It accepts an optional "go"
+// or "v" prefix, followed by dot-separated numbers and an optional
+// pre-release suffix, such as "rc1", "beta2", or "-pre". The string
+// "devel " may precede the version, which indicates a development
+// version before the given release. Anything following a "-" or
+// "+" is ignored, except that it makes the version a pre-release.
+func parseVersion(x string) (version, bool) {
+	var v version
+	if rest, ok := strings.CutPrefix(x, "devel "); ok {
+		v.pre = preDevel
+		x = rest
+	}
+	if rest, ok := strings.CutPrefix(x, "go"); ok {
+		x = rest
+	} else if rest, ok := strings.CutPrefix(x, "v"); ok {
+		x = rest
+	}
+
+	// Parse numeric components.
+	for {
+		end := 0
+		for end < len(x) && '0' <= x[end] && x[end] <= '9' {
+			end++
+		}
+		if end == 0 {
+			return version{}, false
+		}
+		n, err := strconv.Atoi(x[:end])
+		if err != nil {
+			return version{}, false
+		}
+		v.nums = append(v.nums, n)
+		x = x[end:]
+		if len(x) < 2 || x[0] != '.' || !('0' <= x[1] && x[1] <= '9') {
+			break
+		}
+		x = x[1:]
+	}
+
+	// Parse pre-release suffix.
+	if x == "" {
+		return v, true
+	}
+	if x[0] == '-' || x[0] == '+' {
+		if v.pre == 0 && x[0] == '-' {
+			// Semver pre-release.
+			v.pre = preAlpha
+		}
+		return v, true
+	}
+	for _, suffix := range []struct {
+		name string
+		pre  int
+	}{{"alpha", preAlpha}, {"beta", preBeta}, {"rc", preRC}} {
+		if rest, ok := strings.CutPrefix(x, suffix.name); ok {
+			if v.pre == 0 {
+				v.pre = suffix.pre
+			}
+			end := 0
+			for end < len(rest) && '0' <= rest[end] && rest[end] <= '9' {
+				end++
+			}
+			v.preNum, _ = strconv.Atoi(rest[:end])
+			rest = rest[end:]
+			if rest == "" || rest[0] == '-' || rest[0] == '+' || rest[0] == '.' {
+				return v, true
+			}
+			return version{}, false
+		}
+	}
+	return version{}, false
+}
+
+// compareVersions returns <0, 0, or >0 if a is before, equal to, or
+// after b. Missing numeric components are treated as 0, so "1.2" and
+// "1.2.0" are equal.
+func compareVersions(a, b version) int {
+	for i := 0; i < len(a.nums) || i < len(b.nums); i++ {
+		var x, y int
+		if i < len(a.nums) {
+			x = a.nums[i]
+		}
+		if i < len(b.nums) {
+			y = b.nums[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	if a.pre != b.pre {
+		return a.pre - b.pre
+	}
+	return a.preNum - b.preNum
+}
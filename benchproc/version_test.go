@@ -0,0 +1,64 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchproc
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	for _, bad := range []string{"", "go", "v", "abc", "1.x", "go1.21foo", "1.2rc1x"} {
+		if v, ok := parseVersion(bad); ok {
+			t.Errorf("parseVersion(%q) = %+v, want failure", bad, v)
+		}
+	}
+
+	// Versions in increasing order. Versions on the same line are
+	// equal.
+	order := [][]string{
+		{"0.9"},
+		{"devel go1.21-abcdef"},
+		{"go1.21rc1"},
+		{"go1.21rc2"},
+		{"go1.21", "go1.21.0", "1.21", "v1.21.0"},
+		{"go1.21.3", "1.21.3+meta"},
+		{"v1.22.0-pre", "v1.22.0-beta.1"},
+		{"go1.22beta1"},
+		{"1.22"},
+		{"1.100"},
+		{"2"},
+	}
+	type entry struct {
+		s    string
+		rank int
+	}
+	var all []entry
+	for rank, vs := range order {
+		for _, s := range vs {
+			all = append(all, entry{s, rank})
+		}
+	}
+	sign := func(x int) int {
+		switch {
+		case x < 0:
+			return -1
+		case x > 0:
+			return 1
+		}
+		return 0
+	}
+	for _, a := range all {
+		av, ok := parseVersion(a.s)
+		if !ok {
+			t.Errorf("parseVersion(%q) failed", a.s)
+			continue
+		}
+		for _, b := range all {
+			bv, _ := parseVersion(b.s)
+			want := sign(a.rank - b.rank)
+			if got := sign(compareVersions(av, bv)); got != want {
+				t.Errorf("compareVersions(%q, %q) = %d, want %d", a.s, b.s, got, want)
+			}
+		}
+	}
+}
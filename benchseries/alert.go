@@ -0,0 +1,254 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// AlertOptions configures ComparisonSeries.Alerts.
+type AlertOptions struct {
+	// Window is the maximum number of points before a regression
+	// used as its baseline. If 0, it is 10.
+	Window int
+
+	// Confirmations is the number of consecutive latest points that
+	// must all be regressed to raise an alert. If 0, it is 3.
+	Confirmations int
+
+	// Threshold is the minimum relative change from the baseline
+	// that counts as a regression.
+	Threshold float64
+}
+
+// minBaseline is the fewest points of baseline needed for an alert.
+const minBaseline = 3
+
+// An Alert is a regression of one benchmark that persists through the
+// latest series points.
+type Alert struct {
+	Unit      string `json:"unit"`
+	Benchmark string `json:"benchmark"`
+
+	// Onset is the first regressed series point, and OnsetHash is
+	// the numerator hash there. Latest is the last series point.
+	Onset     string `json:"onset"`
+	OnsetHash string `json:"onsethash"`
+	Latest    string `json:"latest"`
+	Points    int    `json:"points"` // Number of regressed points from Onset to Latest.
+
+	// Baseline and Current are the median ratios of the baseline
+	// window and of the regressed points, and Magnitude is
+	// Current/Baseline - 1.
+	Baseline  float64 `json:"baseline"`
+	Current   float64 `json:"current"`
+	Magnitude float64 `json:"magnitude"`
+
+	// Suppressed indicates the alert matches an Acknowledgement.
+	Suppressed bool `json:"suppressed"`
+}
+
+// An Acknowledgement suppresses alerts whose onset is at Series, or,
+// if Until is non-empty, from Series through Until. It applies to
+// alerts for Benchmark, or for all benchmarks if Benchmark is empty.
+type Acknowledgement struct {
+	Benchmark string `json:"benchmark,omitempty"`
+	Series    string `json:"series"`
+	Until     string `json:"until,omitempty"`
+}
+
+// ParseAcknowledgement parses an Acknowledgement of the form
+// "benchmark@series" or "benchmark@series..until", where the
+// benchmark may be empty. The series points are normalized with
+// NormalizeDateString.
+func ParseAcknowledgement(s string) (Acknowledgement, error) {
+	var a Acknowledgement
+	i := strings.LastIndex(s, "@")
+	if i < 0 {
+		return a, fmt.Errorf("acknowledgement %q: want benchmark@series or benchmark@series..until", s)
+	}
+	a.Benchmark = s[:i]
+	from, until, _ := strings.Cut(s[i+1:], "..")
+	var err error
+	if a.Series, err = NormalizeDateString(from); err != nil {
+		return a, fmt.Errorf("acknowledgement %q: %w", s, err)
+	}
+	if until != "" {
+		if a.Until, err = NormalizeDateString(until); err != nil {
+			return a, fmt.Errorf("acknowledgement %q: %w", s, err)
+		}
+	}
+	return a, nil
+}
+
+func (a Acknowledgement) String() string {
+	s := a.Benchmark + "@" + a.Series
+	if a.Until != "" {
+		s += ".." + a.Until
+	}
+	return s
+}
+
+// matches reports whether a suppresses alerts for benchmark with the
+// given onset.
+func (a Acknowledgement) matches(benchmark, onset string) bool {
+	if a.Benchmark != "" && a.Benchmark != benchmark {
+		return false
+	}
+	if a.Until == "" {
+		return onset == a.Series
+	}
+	return a.Series <= onset && onset <= a.Until
+}
+
+// Acknowledge adds a to the acknowledgements of cs, unless it's
+// already there. Acknowledgements are saved with cs, so alerts stay
+// suppressed in later runs.
+func (cs *ComparisonSeries) Acknowledge(a Acknowledgement) {
+	for _, b := range cs.Acknowledged {
+		if a == b {
+			return
+		}
+	}
+	cs.Acknowledged = append(cs.Acknowledged, a)
+}
+
+// Alerts returns the regressions in cs that persist through the latest
+// series points, one per benchmark at most, including those suppressed
+// by acknowledgements. Like StepChanges, it doesn't report the
+// GeomeanBenchmark.
+//
+// A benchmark is regressed from an onset point if it and every later
+// defined point differ from the median of the baseline window of up to
+// opts.Window points before the onset by at least opts.Threshold, in
+// the worse direction according to cs.Better (or the same direction,
+// if that's unknown), and their confidence intervals lie outside the
+// baseline's noise band. The noise band is the larger of twice the
+// standard deviation of the baseline, estimated from the median
+// absolute deviation, and the baseline's median interval half-width.
+// An alert requires at least opts.Confirmations regressed points and
+// reports the earliest onset, so it stays the same as new points
+// arrive, until acknowledged. AddSummaries must be called first.
+func (cs *ComparisonSeries) Alerts(opts AlertOptions) []*Alert {
+	window, confirmations := opts.Window, opts.Confirmations
+	if window == 0 {
+		window = 10
+	}
+	if confirmations == 0 {
+		confirmations = 3
+	}
+	if window < minBaseline {
+		window = minBaseline
+	}
+
+	var alerts []*Alert
+	for j, b := range cs.Benchmarks {
+		if b == GeomeanBenchmark {
+			continue
+		}
+		var pts []int
+		for i := range cs.Series {
+			if sum := cs.Summaries[i][j]; sum.Defined() && finite(sum.Center) && sum.Center > 0 {
+				pts = append(pts, i)
+			}
+		}
+
+		// regressed returns the baseline median and the direction of
+		// the regression if all points from pts[k] on are regressed
+		// relative to the window before pts[k], or 0 if not.
+		regressed := func(k int) (baseline float64, dir int) {
+			if k < minBaseline {
+				return 0, 0
+			}
+			var centers, widths []float64
+			for _, i := range pts[max(0, k-window):k] {
+				sum := cs.Summaries[i][j]
+				centers = append(centers, sum.Center)
+				widths = append(widths, (sum.High-sum.Low)/2)
+			}
+			sort.Float64s(centers)
+			sort.Float64s(widths)
+			m := median(centers)
+			var devs []float64
+			for _, c := range centers {
+				devs = append(devs, math.Abs(c-m))
+			}
+			sort.Float64s(devs)
+			spread := math.Max(2*1.4826*median(devs), median(widths))
+
+			for _, i := range pts[k:] {
+				d := cs.worse(cs.Summaries[i][j], m, spread, opts.Threshold)
+				if d == 0 || dir != 0 && d != dir {
+					return 0, 0
+				}
+				dir = d
+			}
+			return m, dir
+		}
+
+		k, baseline, dir := -1, 0.0, 0
+		for i := len(pts) - confirmations; i >= minBaseline; i-- {
+			if m, d := regressed(i); d != 0 {
+				k, baseline, dir = i, m, d
+			}
+		}
+		if dir == 0 {
+			continue
+		}
+
+		var current []float64
+		for _, i := range pts[k:] {
+			current = append(current, cs.Summaries[i][j].Center)
+		}
+		sort.Float64s(current)
+		a := &Alert{
+			Unit:      cs.Unit,
+			Benchmark: b,
+			Onset:     cs.Series[pts[k]],
+			OnsetHash: cs.HashPairs[cs.Series[pts[k]]].NumHash,
+			Latest:    cs.Series[pts[len(pts)-1]],
+			Points:    len(pts) - k,
+			Baseline:  baseline,
+			Current:   median(current),
+		}
+		a.Magnitude = a.Current/a.Baseline - 1
+		for _, ack := range cs.Acknowledged {
+			if ack.matches(b, a.Onset) {
+				a.Suppressed = true
+			}
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts
+}
+
+// worse returns +1 if sum is regressed upward from baseline m with the
+// given noise spread, -1 if it's regressed downward, and 0 otherwise.
+// If cs.Better is known, only the worse direction counts.
+func (cs *ComparisonSeries) worse(sum *ComparisonSummary, m, spread, threshold float64) int {
+	rel := sum.Center/m - 1
+	if cs.Better <= 0 && rel >= threshold && sum.Low > m+spread {
+		return 1
+	}
+	if cs.Better >= 0 && -rel >= threshold && sum.High < m-spread {
+		return -1
+	}
+	return 0
+}
+
+// WriteAlertsJSON writes alerts to w as a JSON array.
+func WriteAlertsJSON(w io.Writer, alerts []*Alert) error {
+	if alerts == nil {
+		alerts = []*Alert{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(alerts)
+}
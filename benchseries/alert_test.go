@@ -0,0 +1,151 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestAlerts(t *testing.T) {
+	// noisy alternates around 1 by ±2%, so its baseline has a noise band
+	// wider than the threshold.
+	noisy := func(n int, tail ...float64) []float64 {
+		var s []float64
+		for i := 0; i < n; i++ {
+			s = append(s, 1+0.02*float64(i%2*2-1))
+		}
+		return append(s, tail...)
+	}
+	flat := func(n int, tail ...float64) []float64 {
+		s := make([]float64, n)
+		for i := range s {
+			s[i] = 1
+		}
+		return append(s, tail...)
+	}
+
+	cs := seriesOf(0.005,
+		flat(6, 1.1, 1.1, 1.1),                     // B0: persistent regression, confirmed
+		flat(6, 1, 1.1, 1.1),                       // B1: too few confirmations
+		flat(6, 1.1, 1, 1.1),                       // B2: not consecutive
+		noisy(6, 1.03, 1.03, 1.03),                 // B3: within the noise
+		flat(6, 0.9, 0.9, 0.9),                     // B4: improvement
+		flat(5, 1.1, 0, 1.1, 1.1),                  // B5: missing points are skipped
+		[]float64{0, 0, 0, 0, 1, 1, 1.1, 1.1, 1.1}, // B6: baseline too short
+		flat(6, 1.01, 1.01, 1.01),                  // B7: below the threshold
+		flat(4, 1.1, 1.1, 1.1, 1.1, 1.1),           // B8: onset before the latest confirmations
+	)
+	cs.Better = -1
+	cs.Acknowledge(Acknowledgement{Benchmark: "B8", Series: cs.Series[4]})
+
+	alerts := cs.Alerts(AlertOptions{Window: 4, Confirmations: 3, Threshold: 0.02})
+	want := []struct {
+		bench      string
+		onset      int
+		points     int
+		suppressed bool
+	}{
+		{"B0", 6, 3, false},
+		{"B5", 5, 3, false},
+		{"B8", 4, 5, true},
+	}
+	if len(alerts) != len(want) {
+		t.Fatalf("got %d alerts, want %d: %s", len(alerts), len(want), alertsJSON(t, alerts))
+	}
+	for i, w := range want {
+		a := alerts[i]
+		if a.Benchmark != w.bench || a.Onset != cs.Series[w.onset] || a.Points != w.points || a.Suppressed != w.suppressed {
+			t.Errorf("alert %d: got %+v, want %s onset %s points %d suppressed %v", i, a, w.bench, cs.Series[w.onset], w.points, w.suppressed)
+		}
+		if a.Latest != cs.Series[len(cs.Series)-1] || a.OnsetHash != cs.HashPairs[a.Onset].NumHash {
+			t.Errorf("alert %d: got latest %s hash %s", i, a.Latest, a.OnsetHash)
+		}
+		if math.Abs(a.Magnitude-0.1) > 1e-9 || a.Baseline != 1 {
+			t.Errorf("alert %d: got baseline %v magnitude %v, want 1 and 0.1", i, a.Baseline, a.Magnitude)
+		}
+	}
+
+	// When higher is better, the drop in B4 is the regression.
+	cs.Better = 1
+	alerts = cs.Alerts(AlertOptions{Window: 4, Confirmations: 3, Threshold: 0.02})
+	if len(alerts) != 1 || alerts[0].Benchmark != "B4" || math.Abs(alerts[0].Magnitude+0.1) > 1e-9 {
+		t.Errorf("higher is better: got %s", alertsJSON(t, alerts))
+	}
+
+	// With the direction unknown, changes either way alert.
+	cs.Better = 0
+	if alerts := cs.Alerts(AlertOptions{Window: 4, Confirmations: 3, Threshold: 0.02}); len(alerts) != 4 {
+		t.Errorf("unknown direction: got %s", alertsJSON(t, alerts))
+	}
+
+	// The geomean isn't reported, like in StepChanges.
+	cs = seriesOf(0.005, flat(6, 1.1, 1.1, 1.1), flat(6, 1.1, 1.1, 1.1))
+	cs.Better = -1
+	cs.AddGeomean(0.95, 100)
+	if alerts := cs.Alerts(AlertOptions{Window: 4, Confirmations: 3, Threshold: 0.02}); len(alerts) != 2 || alerts[1].Benchmark != "B1" {
+		t.Errorf("with geomean: got %s", alertsJSON(t, alerts))
+	}
+
+	// The onset stays put as more regressed points arrive.
+	cs = seriesOf(0.005, flat(6, 1.1, 1.1, 1.1, 1.1, 1.1, 1.1, 1.1))
+	cs.Better = -1
+	alerts = cs.Alerts(AlertOptions{Window: 4, Confirmations: 3, Threshold: 0.02})
+	if len(alerts) != 1 || alerts[0].Onset != cs.Series[6] || alerts[0].Points != 7 {
+		t.Errorf("long regression: got %s", alertsJSON(t, alerts))
+	}
+
+	// Acknowledgements survive a JSON round trip.
+	cs.Acknowledge(Acknowledgement{Series: cs.Series[5], Until: cs.Series[7]})
+	cs.Acknowledge(Acknowledgement{Series: cs.Series[5], Until: cs.Series[7]})
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(cs); err != nil {
+		t.Fatal(err)
+	}
+	var cs2 ComparisonSeries
+	if err := json.Unmarshal(buf.Bytes(), &cs2); err != nil {
+		t.Fatal(err)
+	}
+	if len(cs2.Acknowledged) != 1 {
+		t.Fatalf("got acknowledgements %v, want 1", cs2.Acknowledged)
+	}
+	if alerts := cs2.Alerts(AlertOptions{Window: 4, Confirmations: 3, Threshold: 0.02}); len(alerts) != 1 || !alerts[0].Suppressed {
+		t.Errorf("acknowledged window: got %s", alertsJSON(t, alerts))
+	}
+}
+
+func TestParseAcknowledgement(t *testing.T) {
+	for _, test := range []struct {
+		in   string
+		want Acknowledgement
+	}{
+		{"BenchmarkFoo@2022-01-02T00:00:00Z", Acknowledgement{"BenchmarkFoo", "2022-01-02T00:00:00+00:00", ""}},
+		{"@2022-01-02T00:00:00Z..2022-01-09T00:00:00Z", Acknowledgement{"", "2022-01-02T00:00:00+00:00", "2022-01-09T00:00:00+00:00"}},
+		{"a@b@2022-01-02T00:00:00Z", Acknowledgement{"a@b", "2022-01-02T00:00:00+00:00", ""}},
+	} {
+		got, err := ParseAcknowledgement(test.in)
+		if err != nil || got != test.want {
+			t.Errorf("%s: got %+v, %v, want %+v", test.in, got, err, test.want)
+		}
+		if got, err := ParseAcknowledgement(got.String()); err != nil || got != test.want {
+			t.Errorf("%s: String doesn't round trip: got %+v, %v", test.in, got, err)
+		}
+	}
+	for _, bad := range []string{"BenchmarkFoo", "BenchmarkFoo@yesterday", "@2022-01-02T00:00:00Z..later"} {
+		if _, err := ParseAcknowledgement(bad); err == nil {
+			t.Errorf("%s: want error", bad)
+		}
+	}
+}
+
+func alertsJSON(t *testing.T, alerts []*Alert) string {
+	var buf bytes.Buffer
+	if err := WriteAlertsJSON(&buf, alerts); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"golang.org/x/perf/benchfmt"
+	"golang.org/x/perf/benchmath"
 	"golang.org/x/perf/benchproc"
 )
 
@@ -36,6 +37,8 @@ type Comparison struct {
 	Date                   string
 	ratios                 []float64 // these are from bootstrapping. Typically 1000ish.
 	Summary                *ComparisonSummary
+	existing               bool // from a pre-existing summary rather than newly read results
+	merged                 bool // replaces or combines a pre-existing summary
 }
 
 // A ComparisonSummary is a summary of the comparison of a particular benchmark measurement
@@ -44,14 +47,22 @@ type Comparison struct {
 // of the original measurement ratios.  Date is the (latest) date at which the measurements
 // were taken.  Present indicates that Low/Center/High/Date are valid; if comparison is non-nil,
 // then there is a bootstrap that can be used or was used to initialize the other fields.
-// (otherwise the source was JSON or a database).
+// (otherwise the source was JSON or a database).  Numerator and Denominator are the raw
+// measurements summarized, if BuilderOptions.KeepValues was set, so that summaries read
+// from JSON can be merged with new measurements and bootstrapped again.  Derived indicates
+// that the summary was computed from other summaries, such as by AddGeomean; derived
+// summaries are not merged with new results or saved by a Store.
 type ComparisonSummary struct {
-	Low        float64     `json:"low"`
-	Center     float64     `json:"center"`
-	High       float64     `json:"high"`
-	Date       string      `json:"date"`
-	Present    bool        `json:"present"` // is this initialized?
-	comparison *Comparison // backlink for K-S computation, also indicates initialization of L/C/H
+	Low         float64     `json:"low"`
+	Center      float64     `json:"center"`
+	High        float64     `json:"high"`
+	Date        string      `json:"date"`
+	Present     bool        `json:"present"` // is this initialized?
+	Numerator   []float64   `json:"numerator,omitempty"`
+	Denominator []float64   `json:"denominator,omitempty"`
+	Derived     bool        `json:"derived,omitempty"`
+	comparison  *Comparison // backlink for K-S computation, also indicates initialization of L/C/H
+	stored      bool        // read from a Store and unchanged since
 }
 
 func (s *ComparisonSummary) Defined() bool {
@@ -82,7 +93,19 @@ type ComparisonSeries struct {
 
 	Residues []StringAndSlice `json:"residues"`
 
-	cells map[SeriesKey]*Comparison
+	// Better and Exact are from the unit metadata. Better is +1 if
+	// higher values of the unit are better, -1 if lower values are
+	// better, and 0 if unknown. Exact is whether the unit has
+	// assume=exact metadata, so its measurements have no noise.
+	Better int  `json:"better,omitempty"`
+	Exact  bool `json:"exact,omitempty"`
+
+	// Acknowledged is the acknowledgements suppressing alerts.
+	Acknowledged []Acknowledgement `json:"acknowledged,omitempty"`
+
+	cells      map[SeriesKey]*Comparison
+	estimator  Estimator // nil means Bootstrap
+	keepValues bool      // save raw measurements in summaries
 }
 
 // SeriesKey is a map key used to index a single cell in a ComparisonSeries.
@@ -134,6 +157,10 @@ type Builder struct {
 
 	Residues map[benchproc.Key]struct{}
 
+	units      benchfmt.UnitMetadataMap
+	estimator  Estimator
+	keepValues bool
+
 	warn func(format string, args ...interface{})
 }
 
@@ -149,6 +176,15 @@ type BuilderOptions struct {
 	DenominatorHash string // the name of the benchmark key that contains the git hash of the denominator (control) toolchain
 	Ignore          string // list of benchmark keys to ignore entirely (e.g. "tip,base,bentstamp,suite")
 	Warn            func(format string, args ...interface{})
+
+	// Estimator summarizes each comparison. If nil, it is Bootstrap.
+	// Units with assume=exact metadata always use Exact.
+	Estimator Estimator
+
+	// KeepValues saves the raw measurements in each ComparisonSummary,
+	// so that later runs can merge new results with DUPE_COMBINE.
+	// It makes saved summaries much larger.
+	KeepValues bool
 }
 
 func BentBuilderOptions() *BuilderOptions {
@@ -243,10 +279,7 @@ func NewBuilder(bo *BuilderOptions) (*Builder, error) {
 		panic("Couldn't parse the unit schema")
 	}
 
-	tableBy, err := parser.Parse(bo.Table, nil)
-	if err != nil {
-		panic("Couldn't parse the table schema")
-	}
+	tableBy := mustParse("-table", bo.Table)
 
 	benchBy, err := parser.Parse(".fullname", nil)
 	if err != nil {
@@ -290,6 +323,9 @@ func NewBuilder(bo *BuilderOptions) (*Builder, error) {
 		hashToOrder:   make(map[benchproc.Key]benchproc.Key),
 		tables:        make(map[unitTableKey]*table),
 		Residues:      make(map[benchproc.Key]struct{}),
+		units:         make(benchfmt.UnitMetadataMap),
+		estimator:     bo.Estimator,
+		keepValues:    bo.KeepValues,
 		warn:          bo.Warn,
 	}, nil
 }
@@ -311,6 +347,9 @@ func (b *Builder) AddFiles(files benchfmt.Files) error {
 	if err := files.Err(); err != nil {
 		return err
 	}
+	for k, m := range files.Units() {
+		b.units[k] = m
+	}
 	return nil
 }
 
@@ -426,18 +465,55 @@ func concat(a, b []float64) []float64 {
 	return append(append([]float64{}, a...), b...)
 }
 
+// combine returns a Cell with the values and residues of a and b,
+// either of which may be nil.
+func combine(a, b *Cell) *Cell {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	return &Cell{
+		Values:   concat(a.Values, b.Values),
+		Residues: union(a.Residues, b.Residues),
+	}
+}
+
+// Merge policies for AllComparisonSeries, which say what to do when more than
+// one comparison is found for the same benchmark and series point.  Duplicates
+// within newly read results are always resolved by keeping the comparison from
+// the newest experiment, except under DUPE_COMBINE; the policies differ in how
+// newly read results are merged with existing summaries.
 const (
+	// DUPE_REPLACE replaces older comparisons with younger ones, whether
+	// they come from existing summaries or newly read results.
 	DUPE_REPLACE = iota
+	// DUPE_COMBINE combines the raw measurements of all comparisons, which
+	// are then bootstrapped together.  Existing summaries must include
+	// their raw measurements; see BuilderOptions.KeepValues.
 	DUPE_COMBINE
+	// DUPE_NEW replaces existing summaries with newly read results,
+	// regardless of their dates.
+	DUPE_NEW
+	// DUPE_ERROR reports an error if newly read results overlap existing
+	// summaries.
+	DUPE_ERROR
 	// TODO DUPE_REPEAT
 )
 
 // AllComparisonSeries converts the accumulated "experiments" into a slice of series of comparisons,
 // with one slice element per goos-goarch-unit.  The experiments need not have occurred in any
-// sensible order; this deals with that, including overlaps (depend on flag, either replaces old with
-// younger or combines, REPLACE IS PREFERRED and works properly with combining old summary data with
-// fresh benchmarking data) and possibly also with previously processed summaries.
+// sensible order; this deals with that, including overlaps (depending on dupeHow, one of the DUPE_
+// merge policies, either replaces old with younger, combines, prefers new results, or fails;
+// REPLACE IS PREFERRED) and possibly also with previously processed summaries.
 func (b *Builder) AllComparisonSeries(existing []*ComparisonSeries, dupeHow int) ([]*ComparisonSeries, error) {
+	switch dupeHow {
+	case DUPE_REPLACE, DUPE_COMBINE, DUPE_NEW, DUPE_ERROR:
+	default:
+		return nil, fmt.Errorf("unknown merge policy %d", dupeHow)
+	}
+
 	old := make(map[string]*ComparisonSeries)
 	for _, cs := range existing {
 		old[cs.Unit] = cs
@@ -459,25 +535,7 @@ func (b *Builder) AllComparisonSeries(existing []*ComparisonSeries, dupeHow int)
 		if o := old[uString]; o != nil {
 			cs = o
 			delete(old, uString)
-
-			cs.cells = make(map[SeriesKey]*Comparison)
-			for i, s := range cs.Series {
-				for j, b := range cs.Benchmarks {
-					if cs.Summaries[i][j].Defined() {
-						sk := SeriesKey{
-							Benchmark: b,
-							Series:    s,
-						}
-						benches[b] = struct{}{}
-						sers[s] = struct{}{}
-						sum := cs.Summaries[i][j]
-						cc := &Comparison{Summary: sum, Date: sum.Date}
-						sum.comparison = cc
-						cs.cells[sk] = cc
-					}
-				}
-			}
-
+			cs.loadCells(benches, sers)
 		} else {
 			cs = &ComparisonSeries{Unit: uString,
 				HashPairs: make(map[string]ComparisonHashes),
@@ -485,8 +543,6 @@ func (b *Builder) AllComparisonSeries(existing []*ComparisonSeries, dupeHow int)
 			}
 		}
 
-		// TODO not handling overlapping samples between "existing" and "newly read" yet.
-
 		// Rearrange into paired comparisons, gathering repeats of same comparison from multiple experiments.
 		for tk, tr := range t.cells {
 			// tk == bench, experiment, tr == baseline, tests, tests == map hash -> cell.
@@ -510,12 +566,19 @@ func (b *Builder) AllComparisonSeries(existing []*ComparisonSeries, dupeHow int)
 					Series:    serString,
 				}
 				cc := cs.cells[sk]
-				if cc == nil || dupeHow == DUPE_REPLACE {
-					if cc == nil || cc.Date < dateString {
+				if cc != nil && cc.existing && dupeHow == DUPE_ERROR {
+					return nil, fmt.Errorf("%s: new results for %s at %s overlap existing summary", uString, benchString, serString)
+				}
+				if cc != nil && cc.existing && dupeHow == DUPE_COMBINE && (cc.Numerator == nil || cc.Denominator == nil) {
+					return nil, fmt.Errorf("%s: cannot combine new results for %s at %s with existing summary without raw measurements", uString, benchString, serString)
+				}
+				if cc == nil || dupeHow != DUPE_COMBINE {
+					if cc == nil || cc.Date < dateString || cc.existing && dupeHow == DUPE_NEW {
 						cc = &Comparison{
 							Numerator:   cell,
 							Denominator: tr.baseline,
 							Date:        dateString,
+							merged:      cc != nil && (cc.existing || cc.merged),
 						}
 						cs.cells[sk] = cc
 					}
@@ -530,22 +593,20 @@ func (b *Builder) AllComparisonSeries(existing []*ComparisonSeries, dupeHow int)
 						}
 					}
 
-				} else { // Current augments, but this will do the wrong thing if one is an old summary; also need to think about "repeat"
+				} else {
 					// augment an existing measurement (i.e., a second experiment on this same datapoint)
 					// fmt.Printf("Augment u:%s,b:%s,ch:%s,cd:%s; cc=%v[n(%d+%d)d(%d+%d)]\n",
 					// 	u.StringValues(), bench.StringValues(), hash.StringValues(), ser.StringValues(),
 					// 	cc, len(cc.Numerator.Values), len(cell.Values), len(cc.Denominator.Values), len(tr.baseline.Values))
-					cc.Numerator = &Cell{
-						Values:   concat(cc.Numerator.Values, cell.Values),
-						Residues: union(cc.Numerator.Residues, cell.Residues),
-					}
-					cc.Denominator = &Cell{
-						Values:   concat(cc.Denominator.Values, tr.baseline.Values),
-						Residues: union(cc.Denominator.Residues, tr.baseline.Residues),
-					}
+					cc.Numerator = combine(cc.Numerator, cell)
+					cc.Denominator = combine(cc.Denominator, tr.baseline)
 					if cc.Date < dateString {
 						cc.Date = dateString
 					}
+					// The combined measurements need a new summary.
+					cc.Summary = nil
+					cc.merged = cc.merged || cc.existing
+					cc.existing = false
 				}
 			}
 		}
@@ -643,12 +704,19 @@ func (b *Builder) AllComparisonSeries(existing []*ComparisonSeries, dupeHow int)
 		}
 
 		cs.Residues = sas
+		cs.estimator = b.estimator
+		cs.keepValues = b.keepValues
+		cs.Better = b.units.GetBetter(u.unit.StringValues())
+		cs.Exact = b.units.GetAssumption(u.unit.StringValues()) == benchmath.AssumeExact
 
 		css = append(css, cs)
 	}
 
 	for _, cs := range existing {
 		if o := old[cs.Unit]; o != nil {
+			cs.loadCells(make(map[string]struct{}), make(map[string]struct{}))
+			cs.estimator = b.estimator
+			cs.keepValues = b.keepValues
 			css = append(css, cs)
 		}
 	}
@@ -656,6 +724,33 @@ func (b *Builder) AllComparisonSeries(existing []*ComparisonSeries, dupeHow int)
 	return css, nil
 }
 
+// loadCells initializes cs.cells from the defined, non-derived
+// summaries of an existing cs, and adds their benchmarks and series
+// points to benches and sers.
+func (cs *ComparisonSeries) loadCells(benches, sers map[string]struct{}) {
+	cs.cells = make(map[SeriesKey]*Comparison)
+	for i, s := range cs.Series {
+		for j, b := range cs.Benchmarks {
+			if sum := cs.Summaries[i][j]; sum.Defined() && !sum.Derived {
+				sk := SeriesKey{
+					Benchmark: b,
+					Series:    s,
+				}
+				benches[b] = struct{}{}
+				sers[s] = struct{}{}
+				sum := cs.Summaries[i][j]
+				cc := &Comparison{Summary: sum, Date: sum.Date, existing: true}
+				if len(sum.Numerator) > 0 && len(sum.Denominator) > 0 {
+					cc.Numerator = &Cell{Values: sum.Numerator, Residues: make(map[benchproc.Key]struct{})}
+					cc.Denominator = &Cell{Values: sum.Denominator, Residues: make(map[benchproc.Key]struct{})}
+				}
+				sum.comparison = cc
+				cs.cells[sk] = cc
+			}
+		}
+	}
+}
+
 func sortStringSet(m map[string]struct{}) []string {
 	var s []string
 	for k := range m {
@@ -746,17 +841,7 @@ func ratio(nu, de *Cell, confidence float64, r *rand.Rand, ratios []float64) (ce
 	for i := 0; i < N; i++ {
 		nu.resampleInto(r, rnu)
 		de.resampleInto(r, rde)
-		den := median(rde)
-		if den == 0 {
-			num := median(rnu)
-			if num >= 0 {
-				ratios[i] = (num + 1)
-			} else {
-				ratios[i] = (num - 1)
-			}
-		} else {
-			ratios[i] = median(rnu) / den
-		}
+		ratios[i] = relative(median(rnu), median(rde))
 	}
 	sort.Float64s(ratios)
 	p := (1 - confidence) / 2
@@ -846,17 +931,6 @@ func ChangeScore(l1, c1, h1, l2, c2, h2 float64) float64 {
 	}
 }
 
-type compareFn func(c *Comparison) (center, low, high float64)
-
-func withBootstrap(confidence float64, N int) compareFn {
-	return func(c *Comparison) (center, low, high float64) {
-		c.ratios = make([]float64, N, N)
-		r := rand.New(rand.NewSource(c.Numerator.hash() * c.Denominator.hash()))
-		center, low, high = ratio(c.Numerator, c.Denominator, confidence, r, c.ratios)
-		return
-	}
-}
-
 // KSov returns the size-adjusted Kolmogorov-Smirnov statistic,
 // equal to D_{n,m} / sqrt((n+m)/n*m).  The result can be compared
 // to c(α) where α is the level at which the null hypothesis is rejected.
@@ -868,6 +942,10 @@ func withBootstrap(confidence float64, N int) compareFn {
 // https://en.wikipedia.org/wiki/Kolmogorov%E2%80%93Smirnov_test#Two-sample_Kolmogorov%E2%80%93Smirnov_test
 func (a *ComparisonSummary) KSov(b *ComparisonSummary) float64 {
 	// TODO Kolmogorov-Smirnov hasn't worked that well
+	if a.comparison == nil || b.comparison == nil || len(a.comparison.ratios) == 0 || len(b.comparison.ratios) == 0 {
+		// Not bootstrapped.
+		return 0
+	}
 	ra, rb := a.comparison.ratios, b.comparison.ratios
 	ia, ib := 0, 0
 	la, lb := len(ra), len(rb)
@@ -895,28 +973,61 @@ func (a *ComparisonSummary) KSov(b *ComparisonSummary) float64 {
 // HeurOverlap computes a heuristic overlap between two confidence intervals
 func (a *ComparisonSummary) HeurOverlap(b *ComparisonSummary, threshold float64) float64 {
 	if a.Low == a.High && b.Low == b.High {
-		ca, cb, sign := a.Center, b.Center, 100.0
-		if cb < ca {
-			ca, cb, sign = cb, ca, -100.0
-		}
-		if ca == 0 {
-			if cb > threshold {
-				return sign
-			}
-		} else if (cb-ca)/ca > threshold {
+		return thresholdChange(a.Center, b.Center, threshold)
+	}
+	return ChangeScore(a.Low, a.Center, a.High, b.Low, b.Center, b.High)
+}
+
+// thresholdChange returns ±100 if the relative change from ca to cb
+// exceeds threshold, and 0 otherwise.
+func thresholdChange(ca, cb, threshold float64) float64 {
+	sign := 100.0
+	if cb < ca {
+		ca, cb, sign = cb, ca, -100.0
+	}
+	if ca == 0 {
+		if cb > threshold {
 			return sign
 		}
-		return 0
+	} else if (cb-ca)/ca > threshold {
+		return sign
 	}
-	return ChangeScore(a.Low, a.Center, a.High, b.Low, b.Center, b.High)
+	return 0
 }
 
-// AddSummaries computes the summary data (bootstrapped estimated of the specified
-// confidence interval) for the comparison series cs.  The 3rd parameter N specifies
-// the number of sampled bootstraps to use; 1000 is recommended, but 500 is good enough
-// for testing.
+// change returns the HeurOverlap change score from a to b, except
+// that changes in exact units always use the threshold. Increases are
+// positive.
+func (cs *ComparisonSeries) change(a, b *ComparisonSummary, threshold float64) float64 {
+	if cs.Exact {
+		return thresholdChange(a.Center, b.Center, threshold)
+	}
+	return a.HeurOverlap(b, threshold)
+}
+
+// changeScore returns cs.change(a, b, threshold), oriented so that
+// improvements are positive and regressions are negative if cs.Better
+// is known.
+func (cs *ComparisonSeries) changeScore(a, b *ComparisonSummary, threshold float64) float64 {
+	ch := cs.change(a, b, threshold)
+	if cs.Better < 0 {
+		ch = -ch
+	}
+	return ch
+}
+
+// AddSummaries computes the summary data (estimates of the specified
+// confidence interval) for the comparison series cs, using the Estimator
+// from the BuilderOptions, or Exact if the unit's metadata says assume=exact.
+// The default Estimator is Bootstrap(N); 1000 is recommended for N, but 500
+// is good enough for testing.
 func (cs *ComparisonSeries) AddSummaries(confidence float64, N int) {
-	fn := withBootstrap(confidence, N)
+	fn := cs.estimator
+	if cs.Exact {
+		fn = Exact
+	} else if fn == nil {
+		fn = Bootstrap(N)
+	}
 	var tab [][]*ComparisonSummary
 	for _, s := range cs.Series {
 		row := []*ComparisonSummary{}
@@ -928,7 +1039,10 @@ func (cs *ComparisonSeries) AddSummaries(confidence float64, N int) {
 					c.Summary = sum
 					sum.Present = c.Denominator != nil
 					if sum.Present {
-						sum.Center, sum.Low, sum.High = fn(c)
+						sum.Center, sum.Low, sum.High = fn(c, confidence)
+						if cs.keepValues {
+							sum.Numerator, sum.Denominator = c.Numerator.Values, c.Denominator.Values
+						}
 					}
 				}
 				row = append(row, sum)
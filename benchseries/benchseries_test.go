@@ -1,8 +1,12 @@
 package benchseries
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"strconv"
 	"testing"
 
 	"golang.org/x/perf/benchfmt"
@@ -247,3 +251,192 @@ func TestMissingDenominator(t *testing.T) {
 		t.Fatalf("Expect 1 comparison, got: %+v", len(got.Summaries))
 	}
 }
+
+func TestMergePolicies(t *testing.T) {
+	opts := &BuilderOptions{
+		Filter:          ".unit:/.*/",
+		Series:          "numerator-hash-time",
+		Experiment:      "runstamp",
+		Compare:         "compare",
+		Numerator:       "numerator",
+		Denominator:     "denominator",
+		NumeratorHash:   "numerator-hash",
+		DenominatorHash: "denominator-hash",
+		Warn: func(format string, args ...interface{}) {
+			t.Errorf("benchseries warning: %s", fmt.Sprintf(format, args...))
+		},
+		KeepValues: true,
+	}
+	// build returns the comparison series for an experiment at
+	// runstamp with the given numerator and denominator values,
+	// merged with existing.
+	build := func(existing []*ComparisonSeries, dupeHow int, runstamp string, num, den []float64) ([]*ComparisonSeries, error) {
+		builder, err := NewBuilder(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		add := func(compare string, v float64) {
+			builder.Add(&benchfmt.Result{
+				Config: []benchfmt.Config{
+					makeConfig("compare", compare),
+					makeConfig("runstamp", runstamp),
+					makeConfig("numerator-hash", "abcdef0123456789"),
+					makeConfig("denominator-hash", "9876543219fedcba"),
+					makeConfig("numerator-hash-time", "2020-02-02T00:00:00Z"),
+				},
+				Name:   []byte("Foo"),
+				Iters:  10,
+				Values: []benchfmt.Value{{Value: v, Unit: "sec"}},
+			})
+		}
+		for _, v := range num {
+			add("numerator", v)
+		}
+		for _, v := range den {
+			add("denominator", v)
+		}
+		css, err := builder.AllComparisonSeries(existing, dupeHow)
+		if err != nil {
+			return nil, err
+		}
+		for _, cs := range css {
+			cs.AddSummaries(0.95, 100)
+		}
+		return css, nil
+	}
+	// existing returns a JSON round trip of an experiment on
+	// 2020-01-02 with ratio 2.
+	existing := func() []*ComparisonSeries {
+		css, err := build(nil, DUPE_REPLACE, "2020-01-02T00:00:00Z", []float64{20, 20, 20}, []float64{10, 10, 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		js, err := json.Marshal(css)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []*ComparisonSeries
+		if err := json.Unmarshal(js, &out); err != nil {
+			t.Fatal(err)
+		}
+		if got := out[0].Summaries[0][0].Numerator; !reflect.DeepEqual(got, []float64{20, 20, 20}) {
+			t.Fatalf("raw numerator values not saved: got %v", got)
+		}
+		return out
+	}
+	check := func(name string, dupeHow int, runstamp string, wantNum []float64, wantCenter float64) {
+		t.Helper()
+		css, err := build(existing(), dupeHow, runstamp, []float64{30, 30, 30}, []float64{10, 10, 10})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			return
+		}
+		sum := css[0].Summaries[0][0]
+		if !reflect.DeepEqual(sum.Numerator, wantNum) || sum.Center != wantCenter {
+			t.Errorf("%s: got numerator %v center %v, want %v %v", name, sum.Numerator, sum.Center, wantNum, wantCenter)
+		}
+	}
+
+	check("replace newer", DUPE_REPLACE, "2020-01-03T00:00:00Z", []float64{30, 30, 30}, 3)
+	check("replace older", DUPE_REPLACE, "2020-01-01T00:00:00Z", []float64{20, 20, 20}, 2)
+	check("new older", DUPE_NEW, "2020-01-01T00:00:00Z", []float64{30, 30, 30}, 3)
+	check("combine", DUPE_COMBINE, "2020-01-01T00:00:00Z", []float64{20, 20, 20, 30, 30, 30}, 2.5)
+
+	if _, err := build(existing(), DUPE_ERROR, "2020-01-03T00:00:00Z", []float64{30}, []float64{10}); err == nil {
+		t.Errorf("error: want error for overlapping results")
+	}
+
+	// Combining requires raw values.
+	old := existing()
+	old[0].Summaries[0][0].Numerator = nil
+	if _, err := build(old, DUPE_COMBINE, "2020-01-03T00:00:00Z", []float64{30}, []float64{10}); err == nil {
+		t.Errorf("combine: want error for existing summary without raw values")
+	}
+
+	// Raw values are only kept if requested.
+	opts.KeepValues = false
+	css, err := build(nil, DUPE_REPLACE, "2020-01-02T00:00:00Z", []float64{20, 20, 20}, []float64{10, 10, 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := css[0].Summaries[0][0]; sum.Numerator != nil || sum.Denominator != nil {
+		t.Errorf("without KeepValues: got raw values %v / %v", sum.Numerator, sum.Denominator)
+	}
+}
+
+func TestExistingWithoutNewResults(t *testing.T) {
+	// A unit with no new results keeps its existing summaries.
+	builder, err := NewBuilder(DefaultBuilderOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := seriesOf(0.01, []float64{1, 1.5})
+	css, err := builder.AllComparisonSeries([]*ComparisonSeries{old}, DUPE_REPLACE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(css) != 1 {
+		t.Fatalf("got %d series, want 1", len(css))
+	}
+	css[0].AddSummaries(0.95, 100)
+	if sum := css[0].Summaries[1][0]; !sum.Defined() || sum.Center != 1.5 {
+		t.Errorf("got summary %+v, want center 1.5", sum)
+	}
+}
+
+func TestChangeScore(t *testing.T) {
+	a := &ComparisonSummary{Low: 0.99, Center: 1, High: 1.01, Present: true}
+	b := &ComparisonSummary{Low: 1.02, Center: 1.03, High: 1.04, Present: true}
+	cs := &ComparisonSeries{}
+	up := cs.changeScore(a, b, 0.05)
+	if up <= 1 {
+		t.Errorf("unknown direction: got %v, want > 1 for an increase", up)
+	}
+
+	// An increase is a regression if lower is better.
+	cs.Better = -1
+	if got := cs.changeScore(a, b, 0.05); got != -up {
+		t.Errorf("lower is better: got %v, want %v", got, -up)
+	}
+	cs.Better = 1
+	if got := cs.changeScore(a, b, 0.05); got != up {
+		t.Errorf("higher is better: got %v, want %v", got, up)
+	}
+
+	// Exact units compare centers against the threshold, even if
+	// the intervals are not empty.
+	cs.Exact = true
+	if got := cs.changeScore(a, b, 0.05); got != 0 {
+		t.Errorf("exact below threshold: got %v, want 0", got)
+	}
+	if got := cs.changeScore(a, b, 0.02); got != 100 {
+		t.Errorf("exact above threshold: got %v, want 100", got)
+	}
+}
+
+func TestCsvChangeColumns(t *testing.T) {
+	cs := seriesOf(0.01, []float64{1, 1.03})
+	cs.Better = -1
+	var buf bytes.Buffer
+	cs.ToCsvBootstrapped(&buf, CSV_CHANGE_HEU|CSV_CHANGE_BETTER, 0.05)
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rows[0][:4], []string{"sec/op", "B0", "change_heur", "change_better"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got header %v, want %v", got, want)
+	}
+	// The heuristic is positive for an increase, and the oriented
+	// column is negative because lower is better.
+	heu, err := strconv.ParseFloat(rows[2][2], 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	better, err := strconv.ParseFloat(rows[2][3], 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if heu <= 0 || better != -heu {
+		t.Errorf("got change_heur %v and change_better %v, want positive and its negation", heu, better)
+	}
+}
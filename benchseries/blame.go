@@ -0,0 +1,205 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// A StepChange is a set of change points in one ComparisonSeries that
+// start at the same series point, together with the range of
+// numerator commits that must contain the cause.
+type StepChange struct {
+	Unit string `json:"unit"`
+
+	// LastGood and FirstBad are the series points bracketing the
+	// change. LastGood is the earliest last point at the old level
+	// of any affected benchmark, so the cause is in the numerator
+	// commits after LastGoodHash up to and including FirstBadHash.
+	LastGood     string `json:"lastgood"`
+	FirstBad     string `json:"firstbad"`
+	LastGoodHash string `json:"lastgoodhash"`
+	FirstBadHash string `json:"firstbadhash"`
+
+	// Geomean is the relative change in the geomean of all
+	// benchmarks with a summary at FirstBad, counting unaffected
+	// benchmarks as unchanged.
+	Geomean float64 `json:"geomean"`
+
+	// Groups is the affected benchmarks, grouped by the size of
+	// their change, largest first.
+	Groups []*MagnitudeGroup `json:"groups"`
+}
+
+// A MagnitudeGroup is a set of change points whose absolute
+// relative change is in [Min, Max). If Max is 0, there is no upper
+// bound.
+type MagnitudeGroup struct {
+	Min     float64       `json:"min"`
+	Max     float64       `json:"max"`
+	Changes []ChangePoint `json:"changes"`
+}
+
+// Label returns a description of the range of g, such as "5%-10%".
+func (g *MagnitudeGroup) Label() string {
+	if g.Max == 0 {
+		return fmt.Sprintf(">=%.4g%%", g.Min*100)
+	}
+	return fmt.Sprintf("%.4g%%-%.4g%%", g.Min*100, g.Max*100)
+}
+
+// magnitudeBounds are the lower bounds of each MagnitudeGroup. The
+// last group's lower bound is the threshold passed to StepChanges.
+var magnitudeBounds = []float64{0.2, 0.1, 0.05}
+
+// StepChanges returns the significant step changes in cs, ordered by
+// series. It uses ChangePoints(penalty) to find change points and
+// keeps those with at least the given confidence and whose absolute
+// relative change is at least threshold. Changes in the
+// GeomeanBenchmark are not included. AddSummaries must be called
+// first.
+func (cs *ComparisonSeries) StepChanges(penalty, confidence, threshold float64) []*StepChange {
+	bySeries := make(map[int]*StepChange)
+	prev := make(map[int]int)
+	for _, cps := range cs.ChangePoints(penalty) {
+		for _, cp := range cps {
+			if cp.Benchmark == GeomeanBenchmark || cp.Confidence < confidence || math.Abs(cp.Magnitude) < threshold {
+				continue
+			}
+			sc := bySeries[cp.Index]
+			if sc == nil {
+				sc = &StepChange{Unit: cs.Unit, FirstBad: cp.Series}
+				bySeries[cp.Index] = sc
+				prev[cp.Index] = cp.Prev
+			} else if cp.Prev < prev[cp.Index] {
+				prev[cp.Index] = cp.Prev
+			}
+			sc.add(cp, threshold)
+		}
+	}
+
+	var out []*StepChange
+	for i, sc := range bySeries {
+		sc.LastGood = cs.Series[prev[i]]
+		sc.LastGoodHash = cs.HashPairs[sc.LastGood].NumHash
+		sc.FirstBadHash = cs.HashPairs[sc.FirstBad].NumHash
+		sc.Geomean = cs.stepGeomean(i, sc)
+		for _, g := range sc.Groups {
+			sort.SliceStable(g.Changes, func(a, b int) bool {
+				return math.Abs(g.Changes[a].Magnitude) > math.Abs(g.Changes[b].Magnitude)
+			})
+		}
+		out = append(out, sc)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].FirstBad < out[b].FirstBad })
+	return out
+}
+
+// add adds cp to the appropriate MagnitudeGroup of sc.
+func (sc *StepChange) add(cp ChangePoint, threshold float64) {
+	mag := math.Abs(cp.Magnitude)
+	lo, hi := threshold, 0.0
+	for _, b := range magnitudeBounds {
+		if mag >= b {
+			lo = b
+			break
+		}
+		hi = b
+	}
+	for _, g := range sc.Groups {
+		if g.Min == lo {
+			g.Changes = append(g.Changes, cp)
+			return
+		}
+	}
+	sc.Groups = append(sc.Groups, &MagnitudeGroup{Min: lo, Max: hi, Changes: []ChangePoint{cp}})
+	sort.Slice(sc.Groups, func(a, b int) bool { return sc.Groups[a].Min > sc.Groups[b].Min })
+}
+
+// stepGeomean returns the relative change in the geomean of all
+// benchmarks with a summary at series index i due to sc.
+func (cs *ComparisonSeries) stepGeomean(i int, sc *StepChange) float64 {
+	affected := make(map[string]float64)
+	for _, g := range sc.Groups {
+		for _, cp := range g.Changes {
+			affected[cp.Benchmark] = cp.After / cp.Before
+		}
+	}
+	sum, n := 0.0, 0
+	for j, b := range cs.Benchmarks {
+		if b == GeomeanBenchmark || !cs.Summaries[i][j].Defined() {
+			continue
+		}
+		if r, ok := affected[b]; ok {
+			sum += math.Log(r)
+		}
+		n++
+	}
+	if n == 0 {
+		return 0
+	}
+	return math.Exp(sum/float64(n)) - 1
+}
+
+// WriteStepChangesText writes a plain text report of scs to w.
+func WriteStepChangesText(w io.Writer, scs []*StepChange) error {
+	for _, sc := range scs {
+		if _, err := fmt.Fprintf(w, "%s: %s..%s (%s..%s) geomean %+.2f%%\n", sc.Unit, sc.LastGoodHash, sc.FirstBadHash, sc.LastGood, sc.FirstBad, sc.Geomean*100); err != nil {
+			return err
+		}
+		for _, g := range sc.Groups {
+			if _, err := fmt.Fprintf(w, "\t%s:\n", g.Label()); err != nil {
+				return err
+			}
+			for _, cp := range g.Changes {
+				if _, err := fmt.Fprintf(w, "\t\t%s %+.2f%%\n", cp.Benchmark, cp.Magnitude*100); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// WriteStepChangesMarkdown writes a Markdown report of scs to w, with
+// a section for each step change.
+func WriteStepChangesMarkdown(w io.Writer, scs []*StepChange) error {
+	for _, sc := range scs {
+		if _, err := fmt.Fprintf(w, "## %s: `%s..%s`\n\n", sc.Unit, sc.LastGoodHash, sc.FirstBadHash); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "Between %s and %s, geomean %+.2f%%.\n\n", sc.LastGood, sc.FirstBad, sc.Geomean*100); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "| Change | Benchmark | Before | After | Confidence |\n|---|---|---|---|---|\n"); err != nil {
+			return err
+		}
+		for _, g := range sc.Groups {
+			for _, cp := range g.Changes {
+				if _, err := fmt.Fprintf(w, "| %+.2f%% (%s) | %s | %.4g | %.4g | %.3f |\n", cp.Magnitude*100, g.Label(), cp.Benchmark, cp.Before, cp.After, cp.Confidence); err != nil {
+					return err
+				}
+			}
+		}
+		if _, err := fmt.Fprintf(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteStepChangesJSON writes scs to w as a JSON array.
+func WriteStepChangesJSON(w io.Writer, scs []*StepChange) error {
+	if scs == nil {
+		scs = []*StepChange{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "\t")
+	return encoder.Encode(scs)
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStepChanges(t *testing.T) {
+	// B0 and B1 both step at index 4, but B1 is missing index 3,
+	// so the blame range starts at index 2. B2 is unaffected and
+	// B3 steps at index 6.
+	cs := seriesOf(0.01,
+		[]float64{1, 1, 1, 1, 1.3, 1.3, 1.3, 1.3},
+		[]float64{1, 1, 1, 0, 1.06, 1.06, 1.06, 1.06},
+		[]float64{1, 1, 1, 1, 1, 1, 1, 1},
+		[]float64{1, 1, 1, 1, 1, 1, 0.9, 0.9},
+	)
+	scs := cs.StepChanges(0, 0.99, 0.02)
+	if len(scs) != 2 {
+		t.Fatalf("got %d step changes, want 2", len(scs))
+	}
+
+	sc := scs[0]
+	if sc.LastGood != cs.Series[2] || sc.FirstBad != cs.Series[4] || sc.LastGoodHash != "h02" || sc.FirstBadHash != "h04" {
+		t.Errorf("got range %s..%s (%s..%s), want h02..h04", sc.LastGoodHash, sc.FirstBadHash, sc.LastGood, sc.FirstBad)
+	}
+	if len(sc.Groups) != 2 || sc.Groups[0].Label() != ">=20%" || sc.Groups[1].Label() != "5%-10%" {
+		t.Errorf("got groups %v", sc.Groups)
+	}
+	// Geomean over all four benchmarks: (1.3 * 1.06)^(1/4).
+	if want := 0.0835; sc.Geomean < want-0.001 || sc.Geomean > want+0.001 {
+		t.Errorf("got geomean %v, want ~%v", sc.Geomean, want)
+	}
+	if scs[1].FirstBadHash != "h06" || scs[1].Groups[0].Changes[0].Benchmark != "B3" {
+		t.Errorf("got second change %+v", scs[1])
+	}
+
+	// A high threshold drops the smaller changes.
+	if scs := cs.StepChanges(0, 0.99, 0.2); len(scs) != 1 || len(scs[0].Groups) != 1 || len(scs[0].Groups[0].Changes) != 1 {
+		t.Errorf("threshold 0.2: got %v", scs)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteStepChangesText(&buf, scs); err != nil {
+		t.Fatal(err)
+	}
+	want := "sec/op: h02..h04 (2022-01-03T00:00:00+00:00..2022-01-05T00:00:00+00:00) geomean +8.35%\n\t>=20%:\n\t\tB0 +30.00%\n\t5%-10%:\n\t\tB1 +6.00%\n"
+	if got := buf.String(); !strings.HasPrefix(got, want) {
+		t.Errorf("text: got:\n%swant prefix:\n%s", got, want)
+	}
+
+	buf.Reset()
+	if err := WriteStepChangesMarkdown(&buf, scs); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); !strings.HasPrefix(got, "## sec/op: `h02..h04`\n") || !strings.Contains(got, "| +30.00% (>=20%) | B0 | 1 | 1.3 | 1.000 |\n") {
+		t.Errorf("markdown: got:\n%s", got)
+	}
+
+	buf.Reset()
+	if err := WriteStepChangesJSON(&buf, scs); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []*StepChange
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[0].FirstBadHash != "h04" || decoded[0].Groups[0].Changes[0].Benchmark != "B0" {
+		t.Errorf("JSON round trip: got %s", buf.Bytes())
+	}
+}
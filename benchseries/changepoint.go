@@ -0,0 +1,243 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// A ChangePoint is a point in the series of a single benchmark where
+// the comparison ratio shifts to a new level, for example, the commit
+// that introduced a regression.
+type ChangePoint struct {
+	Benchmark string `json:"benchmark"`
+	// Series is the first point of the series at the new level,
+	// and Index is its index in ComparisonSeries.Series.
+	Series string `json:"series"`
+	Index  int    `json:"index"`
+	// Prev is the index in ComparisonSeries.Series of the last
+	// point of this benchmark at the old level.
+	Prev int `json:"prev"`
+
+	// Before and After are the (geometric, weighted) mean Center
+	// ratios of the segments of the series before and after the
+	// change point.
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+
+	// Magnitude is the relative change, After/Before - 1. For
+	// example, 0.05 is a 5% increase.
+	Magnitude float64 `json:"magnitude"`
+
+	// Confidence is the confidence, in [0, 1], that the segments
+	// on either side of the change point have different levels.
+	Confidence float64 `json:"confidence"`
+}
+
+func (c *ChangePoint) String() string {
+	return fmt.Sprintf("%s @ %s: %+.2f%% (%.4g -> %.4g, confidence %.3f)", c.Benchmark, c.Series, c.Magnitude*100, c.Before, c.After, c.Confidence)
+}
+
+// changeMinSegment is the minimum number of points between change
+// points. A single outlier shouldn't become two change points.
+const changeMinSegment = 2
+
+// changeMinWidth is the minimum relative half-width of a summary's
+// confidence interval, used to keep exact metrics from dominating.
+const changeMinWidth = 0.001
+
+// ChangePoints finds the points in each benchmark's series where the
+// comparison ratio shifts to a new level. It returns a slice of change
+// points for each benchmark, indexed like cs.Benchmarks. AddSummaries
+// must be called first; points with no summary are skipped.
+//
+// Change points are found with PELT (pruned exact linear time)
+// segmentation of the log Center ratios, where each point is weighted
+// by the inverse square of the width of its confidence interval, so
+// noisy points count for less. Single-point outliers are first
+// smoothed away with a 3-point running median. Since the variation between points is
+// usually larger than the bootstrapped intervals suggest, the weights
+// are scaled by an estimate of this overdispersion from the differences
+// of neighboring points. penalty is the cost of adding a change point;
+// larger values find fewer change points. If penalty is <= 0,
+// ChangePoints uses 3*ln(n), where n is the number of points.
+func (cs *ComparisonSeries) ChangePoints(penalty float64) [][]ChangePoint {
+	out := make([][]ChangePoint, len(cs.Benchmarks))
+	for j, b := range cs.Benchmarks {
+		var idx []int
+		var x, sd []float64
+		for i := range cs.Series {
+			if i >= len(cs.Summaries) || j >= len(cs.Summaries[i]) {
+				continue
+			}
+			sum := cs.Summaries[i][j]
+			if !sum.Defined() || sum.Center <= 0 || sum.Low <= 0 || sum.High <= 0 {
+				continue
+			}
+			idx = append(idx, i)
+			x = append(x, math.Log(sum.Center))
+			// Treat the interval as about ±2 standard deviations.
+			sd = append(sd, math.Max((math.Log(sum.High)-math.Log(sum.Low))/4, changeMinWidth))
+		}
+		for _, seg := range segment(x, sd, penalty) {
+			cp := ChangePoint{
+				Benchmark:  b,
+				Series:     cs.Series[idx[seg.at]],
+				Index:      idx[seg.at],
+				Prev:       idx[seg.at-1],
+				Before:     math.Exp(seg.before),
+				After:      math.Exp(seg.after),
+				Confidence: seg.confidence,
+			}
+			cp.Magnitude = cp.After/cp.Before - 1
+			out[j] = append(out[j], cp)
+		}
+	}
+	return out
+}
+
+// changeSplit is a change point found by segment.
+type changeSplit struct {
+	at            int     // index of the first point after the change
+	before, after float64 // weighted means of neighboring segments
+	confidence    float64
+}
+
+// segment returns the change points in x, where sd gives the standard
+// deviation of each point in x.
+func segment(x, sd []float64, penalty float64) []changeSplit {
+	n := len(x)
+	if n < 2*changeMinSegment {
+		return nil
+	}
+	if penalty <= 0 {
+		penalty = 3 * math.Log(float64(n))
+	}
+
+	// Remove single-point outliers with a 3-point running median.
+	// Unlike a running mean, this preserves steps.
+	orig := x
+	x = append([]float64(nil), x...)
+	for i := 1; i+1 < n; i++ {
+		x[i] = median3(orig[i-1], orig[i], orig[i+1])
+	}
+
+	// Estimate overdispersion from the squared, normalized
+	// differences of neighboring points. For a series with no
+	// change points, each is χ²(1), whose median is about 0.455.
+	// Change points only affect a few differences, so the median
+	// is robust to them.
+	d := make([]float64, n-1)
+	for i := range d {
+		dx := x[i+1] - x[i]
+		d[i] = dx * dx / (sd[i]*sd[i] + sd[i+1]*sd[i+1])
+	}
+	sort.Float64s(d)
+	phi := math.Max(1, median(d)/0.455)
+
+	// Prefix sums of the weights, weighted values, and weighted
+	// squared values, so the cost of any segment is O(1).
+	w := make([]float64, n+1)
+	wx := make([]float64, n+1)
+	wxx := make([]float64, n+1)
+	for i := 0; i < n; i++ {
+		wi := 1 / (phi * sd[i] * sd[i])
+		w[i+1] = w[i] + wi
+		wx[i+1] = wx[i] + wi*x[i]
+		wxx[i+1] = wxx[i] + wi*x[i]*x[i]
+	}
+	// cost returns the weighted sum of squared deviations from the
+	// weighted mean of x[s:t].
+	cost := func(s, t int) float64 {
+		sw, swx := w[t]-w[s], wx[t]-wx[s]
+		return wxx[t] - wxx[s] - swx*swx/sw
+	}
+	mean := func(s, t int) float64 {
+		return (wx[t] - wx[s]) / (w[t] - w[s])
+	}
+
+	// PELT. f[t] is the optimal cost of x[:t], and last[t] is the
+	// start of the final segment in that optimal segmentation.
+	f := make([]float64, n+1)
+	last := make([]int, n+1)
+	f[0] = -penalty
+	candidates := []int{0}
+	for t := changeMinSegment; t <= n; t++ {
+		best, bestS := math.Inf(1), -1
+		for _, s := range candidates {
+			if t-s < changeMinSegment {
+				continue
+			}
+			if c := f[s] + cost(s, t) + penalty; c < best {
+				best, bestS = c, s
+			}
+		}
+		if bestS < 0 {
+			f[t] = math.Inf(1)
+			continue
+		}
+		f[t], last[t] = best, bestS
+
+		// Prune candidates that can never be optimal again.
+		keep := candidates[:0]
+		for _, s := range candidates {
+			if t-s < changeMinSegment || f[s]+cost(s, t) <= f[t] {
+				keep = append(keep, s)
+			}
+		}
+		candidates = append(keep, t)
+	}
+
+	// Recover the segment boundaries.
+	var bounds []int
+	for t := n; t > 0; t = last[t] {
+		bounds = append(bounds, t)
+	}
+	bounds = append(bounds, 0)
+	for i, j := 0, len(bounds)-1; i < j; i, j = i+1, j-1 {
+		bounds[i], bounds[j] = bounds[j], bounds[i]
+	}
+
+	var out []changeSplit
+	for i := 1; i+1 < len(bounds); i++ {
+		a, b, c := bounds[i-1], bounds[i], bounds[i+1]
+		m1, m2 := mean(a, b), mean(b, c)
+		z := math.Abs(m2-m1) / math.Sqrt(1/(w[b]-w[a])+1/(w[c]-w[b]))
+		out = append(out, changeSplit{b, m1, m2, math.Erf(z / math.Sqrt2)})
+	}
+	return out
+}
+
+func median3(a, b, c float64) float64 {
+	return math.Max(math.Min(a, b), math.Min(math.Max(a, b), c))
+}
+
+// ChangesReport writes a report of the change points in cs, as
+// computed by ChangePoints(penalty), to out. Change points whose
+// magnitude is less than threshold are omitted.
+func (cs *ComparisonSeries) ChangesReport(out io.Writer, penalty, threshold float64) {
+	fmt.Fprintf(out, "%s\n", cs.Unit)
+	n := 0
+	for _, cps := range cs.ChangePoints(penalty) {
+		for i := range cps {
+			cp := &cps[i]
+			if math.Abs(cp.Magnitude) < threshold {
+				continue
+			}
+			n++
+			fmt.Fprintf(out, "\t%s", cp)
+			if hp, ok := cs.HashPairs[cp.Series]; ok {
+				fmt.Fprintf(out, " %s", hp.NumHash)
+			}
+			fmt.Fprintf(out, "\n")
+		}
+	}
+	if n == 0 {
+		fmt.Fprintf(out, "\tno changes\n")
+	}
+}
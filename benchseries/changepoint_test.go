@@ -0,0 +1,113 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// seriesOf returns a ComparisonSeries with one benchmark per element
+// of centers, each with a summary of the given relative half-width
+// around each center.
+func seriesOf(width float64, centers ...[]float64) *ComparisonSeries {
+	cs := &ComparisonSeries{Unit: "sec/op", HashPairs: make(map[string]ComparisonHashes)}
+	for j := range centers {
+		cs.Benchmarks = append(cs.Benchmarks, fmt.Sprintf("B%d", j))
+	}
+	for i := range centers[0] {
+		s := fmt.Sprintf("2022-01-%02dT00:00:00+00:00", i+1)
+		cs.Series = append(cs.Series, s)
+		cs.HashPairs[s] = ComparisonHashes{NumHash: fmt.Sprintf("h%02d", i), DenHash: "base"}
+		var row []*ComparisonSummary
+		for j := range centers {
+			c := centers[j][i]
+			if c == 0 {
+				row = append(row, &ComparisonSummary{})
+				continue
+			}
+			row = append(row, &ComparisonSummary{Low: c * (1 - width), Center: c, High: c * (1 + width), Present: true})
+		}
+		cs.Summaries = append(cs.Summaries, row)
+	}
+	return cs
+}
+
+func TestChangePoints(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	noisy := func(n int, level float64) []float64 {
+		var out []float64
+		for i := 0; i < n; i++ {
+			out = append(out, level*(1+0.005*r.NormFloat64()))
+		}
+		return out
+	}
+
+	// B0 has a 10% regression at index 10, B1 is flat, and B2 has
+	// a missing point and an improvement at index 15.
+	b0 := append(noisy(10, 1), noisy(10, 1.1)...)
+	b1 := noisy(20, 1)
+	b2 := append(noisy(15, 1.05), noisy(5, 0.95)...)
+	b2[3] = 0
+	cs := seriesOf(0.01, b0, b1, b2)
+
+	cps := cs.ChangePoints(0)
+	if len(cps) != 3 {
+		t.Fatalf("got %d benchmarks, want 3", len(cps))
+	}
+	check := func(j, index int, magnitude float64) {
+		t.Helper()
+		if len(cps[j]) != 1 {
+			t.Errorf("B%d: got %v, want one change point", j, cps[j])
+			return
+		}
+		cp := cps[j][0]
+		if cp.Index != index || cp.Series != cs.Series[index] || cp.Benchmark != cs.Benchmarks[j] {
+			t.Errorf("B%d: got change at %d (%s), want %d", j, cp.Index, cp.Series, index)
+		}
+		if d := cp.Magnitude - magnitude; d < -0.01 || d > 0.01 {
+			t.Errorf("B%d: got magnitude %v, want ~%v", j, cp.Magnitude, magnitude)
+		}
+		if cp.Confidence < 0.99 {
+			t.Errorf("B%d: got confidence %v, want > 0.99", j, cp.Confidence)
+		}
+	}
+	check(0, 10, 0.1)
+	if len(cps[1]) != 0 {
+		t.Errorf("B1: got %v, want no change points", cps[1])
+	}
+	check(2, 15, 0.95/1.05-1)
+
+	// A single outlier is not a change point.
+	b3 := noisy(20, 1)
+	b3[7] = 1.2
+	if cps := seriesOf(0.01, b3).ChangePoints(0); len(cps[0]) != 0 {
+		t.Errorf("outlier: got %v, want no change points", cps[0])
+	}
+
+	// Too few points.
+	if cps := seriesOf(0.01, []float64{1, 2, 2}).ChangePoints(0); len(cps[0]) != 0 {
+		t.Errorf("short series: got %v, want no change points", cps[0])
+	}
+}
+
+func TestChangesReport(t *testing.T) {
+	cs := seriesOf(0.01, []float64{1, 1, 1, 1, 1.2, 1.2, 1.2, 1.2})
+	var buf bytes.Buffer
+	cs.ChangesReport(&buf, 0, 0.02)
+	got := buf.String()
+	if !strings.HasPrefix(got, "sec/op\n\tB0 @ 2022-01-05T00:00:00+00:00: +20.00% (1 -> 1.2, confidence 1.000) h04\n") {
+		t.Errorf("got:\n%s", got)
+	}
+
+	buf.Reset()
+	cs.ChangesReport(&buf, 0, 0.5)
+	if got, want := buf.String(), "sec/op\n\tno changes\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
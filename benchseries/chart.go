@@ -31,6 +31,7 @@ type Point struct {
 	values           plotter.Values
 	changes          []float64
 	changeBVID       []benchValID
+	geomean          float64 // center of the GeomeanBenchmark, or NaN
 }
 
 // Because there are holes in that data, the benchmark index can be larger than the valueIndex
@@ -64,8 +65,16 @@ func Chart(cs []*ComparisonSeries, pngDir, pdfDir, svgDir string, logScale bool,
 			values := make(plotter.Values, 0, len(g.Benchmarks))
 			changes := make([]float64, 0, len(g.Benchmarks))
 			changeBenches := make([]benchValID, 0, len(g.Benchmarks)) // which benchmarks changed?
-			for j := range g.Benchmarks {
+			geomean := math.NaN()
+			for j, b := range g.Benchmarks {
 				sum := g.Summaries[i][j]
+				if b == GeomeanBenchmark {
+					// Drawn as a separate line rather than in the box.
+					if sum.Defined() {
+						geomean = sum.Center
+					}
+					continue
+				}
 				if sum.Defined() {
 					ch := math.NaN()
 					v := sum.Center
@@ -75,7 +84,7 @@ func Chart(cs []*ComparisonSeries, pngDir, pdfDir, svgDir string, logScale bool,
 					if i > 0 {
 						psum := g.Summaries[i-1][j]
 						if psum.Defined() {
-							ch = psum.HeurOverlap(sum, threshold)
+							ch = g.changeScore(psum, sum, threshold)
 						}
 					}
 					changes = append(changes, ch)
@@ -85,7 +94,7 @@ func Chart(cs []*ComparisonSeries, pngDir, pdfDir, svgDir string, logScale bool,
 				}
 			}
 			hp := g.HashPairs[s]
-			selectedPoints = append(selectedPoints, &Point{numHash: hp.NumHash, denHash: hp.DenHash, values: values, changes: changes, changeBVID: changeBenches})
+			selectedPoints = append(selectedPoints, &Point{numHash: hp.NumHash, denHash: hp.DenHash, values: values, changes: changes, changeBVID: changeBenches, geomean: geomean})
 		}
 
 		if len(selectedPoints) == 0 {
@@ -147,20 +156,36 @@ func Chart(cs []*ComparisonSeries, pngDir, pdfDir, svgDir string, logScale bool,
 							prevIndex = psp.valueIndex
 						}
 					}
-					// it should not be the case that a move was recorded, yet there was no match for prevIndex
+					if prevIndex < 0 {
+						// The previous value was not plotted (e.g., it was infinite).
+						return
+					}
 					movers[index] = true
 					moves = append(moves, directedColor{prev: selectedPoints[i-1].values.Value(prevIndex), index: index, change: ch, clr: clr})
 				}
-				if c >= 100 {
+				if !(c > 3) { // catch NaN also.
+					break
+				}
+				if g.Better != 0 {
+					// Red for regressions and green for
+					// improvements, fainter for smaller changes.
+					alpha := uint8(0xff)
+					if c <= 5 {
+						alpha = 0x80
+					}
+					if ch < 0 {
+						noteMove(red(alpha))
+					} else {
+						noteMove(green(alpha))
+					}
+				} else if c >= 100 {
 					noteMove(green(0xff))
 				} else if c > 5 {
 					noteMove(red(0xff))
 				} else if c > 4 {
 					noteMove(purple(0xff))
-				} else if c > 3 {
-					noteMove(blue(0xff))
 				} else {
-					break
+					noteMove(blue(0xff))
 				}
 
 			}
@@ -190,6 +215,29 @@ func Chart(cs []*ComparisonSeries, pngDir, pdfDir, svgDir string, logScale bool,
 			nominalX = append(nominalX, label)
 		}
 		pl.Add(boxes...)
+
+		var geomeans plotter.XYs
+		for i, sp := range selectedPoints {
+			if !math.IsNaN(sp.geomean) && !math.IsInf(sp.geomean, 0) {
+				geomeans = append(geomeans, plotter.XY{X: float64(i), Y: sp.geomean})
+			}
+		}
+		if len(geomeans) > 0 {
+			line, points, err := plotter.NewLinePoints(geomeans)
+			if err != nil {
+				panic(err)
+			}
+			line.Color = orange(0xff)
+			line.Width = vg.Points(2)
+			points.Color = orange(0xff)
+			points.Shape = draw.CircleGlyph{}
+			points.Radius = pointRad
+			pl.Add(line, points)
+			pl.Legend.Add(GeomeanBenchmark, line, points)
+			pl.Legend.TextStyle.Font.Size = 20
+			pl.Legend.Top = true
+			pl.Legend.Left = true
+		}
 		pl.NominalX(nominalX...)
 
 		pl.X.Tick.Width = vg.Points(0.5)
@@ -287,6 +335,9 @@ func green(alpha uint8) color.Color {
 func blue(alpha uint8) color.Color {
 	return color.NRGBA{0, 0, 0xFF, alpha}
 }
+func orange(alpha uint8) color.Color {
+	return color.NRGBA{0xFF, 0x80, 0, alpha}
+}
 func purple(alpha uint8) color.Color {
 	return color.NRGBA{0x99, 0, 0xFF, alpha}
 }
@@ -0,0 +1,172 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/perf/benchproc"
+)
+
+// presets are the named BuilderOptions presets.
+var presets = map[string]func() *BuilderOptions{
+	"bent":    BentBuilderOptions,
+	"default": DefaultBuilderOptions,
+}
+
+// PresetNames returns the names of the BuilderOptions presets, sorted.
+func PresetNames() []string {
+	var names []string
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PresetBuilderOptions returns a new copy of the named BuilderOptions
+// preset: "bent" for BentBuilderOptions or "default" for
+// DefaultBuilderOptions.
+func PresetBuilderOptions(name string) (*BuilderOptions, error) {
+	p, ok := presets[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown preset %q (want one of %s)", name, strings.Join(PresetNames(), ", "))
+	}
+	return p(), nil
+}
+
+// A ConfigSetting is one "key: value" line of a configuration file.
+type ConfigSetting struct {
+	Key, Value string
+	Line       int
+}
+
+// A ConfigError is an error in a configuration file.
+type ConfigError struct {
+	File string
+	Line int
+	Key  string // The key in error, or "" for a syntax error.
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %s: %v", e.File, e.Line, e.Key, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// builderKeys maps configuration keys to the BuilderOptions field they
+// set. The keys match the flags of cmd/benchseries.
+var builderKeys = map[string]func(bo *BuilderOptions) *string{
+	"filter":           func(bo *BuilderOptions) *string { return &bo.Filter },
+	"series":           func(bo *BuilderOptions) *string { return &bo.Series },
+	"table":            func(bo *BuilderOptions) *string { return &bo.Table },
+	"experiment":       func(bo *BuilderOptions) *string { return &bo.Experiment },
+	"compare":          func(bo *BuilderOptions) *string { return &bo.Compare },
+	"numerator":        func(bo *BuilderOptions) *string { return &bo.Numerator },
+	"denominator":      func(bo *BuilderOptions) *string { return &bo.Denominator },
+	"numerator-hash":   func(bo *BuilderOptions) *string { return &bo.NumeratorHash },
+	"denominator-hash": func(bo *BuilderOptions) *string { return &bo.DenominatorHash },
+	"ignore":           func(bo *BuilderOptions) *string { return &bo.Ignore },
+}
+
+// ReadConfig reads the settings in a configuration file. Each
+// non-blank line has the form "key: value", like a benchmark
+// configuration line. Lines starting with "#" are comments. file is
+// used in errors.
+func ReadConfig(r io.Reader, file string) ([]ConfigSetting, error) {
+	var settings []ConfigSetting
+	seen := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(text, ":")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, &ConfigError{file, line, "", fmt.Errorf("expected \"key: value\"")}
+		}
+		if prev, ok := seen[key]; ok {
+			return nil, &ConfigError{file, line, key, fmt.Errorf("already set on line %d", prev)}
+		}
+		seen[key] = line
+		settings = append(settings, ConfigSetting{key, value, line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// LoadConfig reads a configuration file and returns the BuilderOptions
+// it describes. The options start from the preset named by the file's
+// "preset" key, or defaultPreset if there is none, and the builder
+// keys ("filter", "series", "table", "experiment", "compare",
+// "numerator", "denominator", "numerator-hash", "denominator-hash",
+// and "ignore") override fields of the preset, and the filter and
+// projections among them are checked for errors. The remaining settings,
+// such as output options, are returned for the caller to apply and
+// validate. file is used in errors.
+func LoadConfig(r io.Reader, file, defaultPreset string) (*BuilderOptions, []ConfigSetting, error) {
+	settings, err := ReadConfig(r, file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bo, err := PresetBuilderOptions(defaultPreset)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, s := range settings {
+		if s.Key == "preset" {
+			if bo, err = PresetBuilderOptions(s.Value); err != nil {
+				return nil, nil, &ConfigError{file, s.Line, s.Key, err}
+			}
+		}
+	}
+
+	var rest []ConfigSetting
+	for _, s := range settings {
+		if s.Key == "preset" {
+			continue
+		}
+		field, ok := builderKeys[s.Key]
+		if !ok {
+			rest = append(rest, s)
+			continue
+		}
+		switch s.Key {
+		case "filter":
+			if _, err := benchproc.NewFilter(s.Value); err != nil {
+				return nil, nil, &ConfigError{file, s.Line, s.Key, err}
+			}
+		case "numerator", "denominator":
+		default:
+			// The other builder keys are projections. They may
+			// contain filters, which are checked but discarded here.
+			all, err := benchproc.NewFilter("*")
+			if err != nil {
+				panic(err)
+			}
+			var parser benchproc.ProjectionParser
+			if _, err := parser.Parse(s.Value, all); err != nil {
+				return nil, nil, &ConfigError{file, s.Line, s.Key, err}
+			}
+		}
+		*field(bo) = s.Value
+	}
+	return bo, rest, nil
+}
@@ -0,0 +1,63 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	const config = `# Shared settings.
+preset: default
+
+series: numerator_stamp
+table:
+csv: false
+threshold: 0.05
+`
+	bo, rest, err := LoadConfig(strings.NewReader(config), "team.conf", "bent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultBuilderOptions()
+	want.Series = "numerator_stamp"
+	if bo.Series != want.Series || bo.Compare != want.Compare || bo.Table != "" || bo.Ignore != want.Ignore {
+		t.Errorf("got options %+v, want %+v", bo, want)
+	}
+	wantRest := []ConfigSetting{{"csv", "false", 6}, {"threshold", "0.05", 7}}
+	if !reflect.DeepEqual(rest, wantRest) {
+		t.Errorf("got other settings %v, want %v", rest, wantRest)
+	}
+
+	// Without a preset key, the default preset is used.
+	bo, _, err = LoadConfig(strings.NewReader("numerator: new\n"), "team.conf", "bent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bo.Numerator != "new" || bo.Compare != BentBuilderOptions().Compare {
+		t.Errorf("got options %+v, want bent with numerator new", bo)
+	}
+
+	for _, test := range []struct {
+		config, want string
+	}{
+		{"series: a\nnot a setting\n", "c.conf:2: expected \"key: value\""},
+		{"series: a\nseries: b\n", "c.conf:2: series: already set on line 1"},
+		{"\npreset: nope\n", "c.conf:2: preset: unknown preset \"nope\" (want one of bent, default)"},
+		{"filter: (\n", "c.conf:1: filter: syntax error"},
+		{"filter: *\ntable: goos,(\n", "c.conf:2: table: syntax error"},
+		{"series: a@foo\n", "c.conf:1: series: syntax error: unknown order \"foo\""},
+		{"numerator: (\nignore: a@(\n", "c.conf:2: ignore: syntax error"},
+	} {
+		_, _, err := LoadConfig(strings.NewReader(test.config), "c.conf", "bent")
+		var cerr *ConfigError
+		if !errors.As(err, &cerr) || !strings.HasPrefix(err.Error(), test.want) {
+			t.Errorf("%q: got error %v, want %s", test.config, err, test.want)
+		}
+	}
+}
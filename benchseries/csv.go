@@ -24,10 +24,15 @@ const (
 
 	CSV_VALUES CsvOptions = 4
 
-	CSV_CHANGE_HEU CsvOptions = 8  // This is the interval-overlap heuristic
-	CSV_CHANGE_KS  CsvOptions = 16 // This is a Kolmogorov-Smirnov statistic
+	CSV_CHANGE_HEU    CsvOptions = 8  // This is the interval-overlap heuristic
+	CSV_CHANGE_KS     CsvOptions = 16 // This is a Kolmogorov-Smirnov statistic
+	CSV_CHANGE_BETTER CsvOptions = 32 // This is the heuristic, oriented so that regressions are negative
 )
 
+// ToCsvBootstrapped writes cs to out as CSV. With CSV_CHANGE_HEU, each
+// point includes the HeurOverlap change score from the previous point,
+// with threshold used for exact units, and with CSV_CHANGE_BETTER, the
+// same score oriented by cs.Better so that regressions are negative.
 func (cs *ComparisonSeries) ToCsvBootstrapped(out io.Writer, options CsvOptions, threshold float64) {
 	tab, entries := cs.headerAndEntries(options)
 	summaries := cs.Summaries
@@ -47,7 +52,7 @@ func (cs *ComparisonSeries) ToCsvBootstrapped(out io.Writer, options CsvOptions,
 
 				if i > 0 && summaries[i-1][j].Defined() {
 					p := summaries[i-1][j]
-					ch := p.HeurOverlap(sum, threshold)
+					ch := cs.change(p, sum, threshold)
 					changesHeu = append(changesHeu, ch)
 					cks := p.KSov(sum)
 					changesKs = append(changesKs, cks)
@@ -66,6 +71,13 @@ func (cs *ComparisonSeries) ToCsvBootstrapped(out io.Writer, options CsvOptions,
 							entries = append(entries, "")
 						}
 					}
+					if options&CSV_CHANGE_BETTER != 0 {
+						if chb := cs.changeScore(p, sum, threshold); !(math.IsInf(chb, 0) || math.IsNaN(chb)) {
+							entries = append(entries, strof(chb))
+						} else {
+							entries = append(entries, "")
+						}
+					}
 				} else {
 					if options&CSV_CHANGE_HEU != 0 {
 						entries = append(entries, "")
@@ -73,6 +85,9 @@ func (cs *ComparisonSeries) ToCsvBootstrapped(out io.Writer, options CsvOptions,
 					if options&CSV_CHANGE_KS != 0 {
 						entries = append(entries, "")
 					}
+					if options&CSV_CHANGE_BETTER != 0 {
+						entries = append(entries, "")
+					}
 				}
 
 				switch options & CSV_DISTRIBUTION_BITS {
@@ -117,6 +132,9 @@ func (cs *ComparisonSeries) headerAndEntries(options CsvOptions) (tab [][]string
 	if options&CSV_CHANGE_KS != 0 {
 		entriesLen += 6
 	}
+	if options&CSV_CHANGE_BETTER != 0 {
+		entriesLen += 1
+	}
 
 	entries = make([]string, entriesLen, entriesLen) // ratio,  change, +/- or (lo, hi)
 
@@ -129,6 +147,9 @@ func (cs *ComparisonSeries) headerAndEntries(options CsvOptions) (tab [][]string
 		if options&CSV_CHANGE_KS != 0 {
 			hdr = append(hdr, "change_ks")
 		}
+		if options&CSV_CHANGE_BETTER != 0 {
+			hdr = append(hdr, "change_better")
+		}
 		switch options & CSV_DISTRIBUTION_BITS {
 		case CSV_PLAIN:
 		case CSV_DELTA:
@@ -0,0 +1,106 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/aclements/go-moremath/stats"
+)
+
+// An Estimator summarizes a Comparison as an estimate of the ratio of
+// its numerator to its denominator and a confidence interval for that
+// ratio. The Numerator and Denominator of c are non-nil and their
+// values are sorted.
+type Estimator func(c *Comparison, confidence float64) (center, low, high float64)
+
+// Bootstrap returns an Estimator that bootstraps the ratio of the
+// numerator median to the denominator median with N resamples. This
+// is the default, and the only Estimator whose summaries support
+// ComparisonSummary.KSov.
+func Bootstrap(N int) Estimator {
+	return func(c *Comparison, confidence float64) (center, low, high float64) {
+		c.ratios = make([]float64, N, N)
+		r := rand.New(rand.NewSource(c.Numerator.hash() * c.Denominator.hash()))
+		center, low, high = ratio(c.Numerator, c.Denominator, confidence, r, c.ratios)
+		return
+	}
+}
+
+// HodgesLehmann is an Estimator based on the Hodges-Lehmann estimate
+// of the shift between the denominator and numerator, which is the
+// median of all pairwise differences, with the distribution-free
+// confidence interval from the Mann-Whitney U statistic. The shift is
+// made relative to the denominator median.
+func HodgesLehmann(c *Comparison, confidence float64) (center, low, high float64) {
+	nu, de := c.Numerator.Values, c.Denominator.Values
+	diffs := make([]float64, 0, len(nu)*len(de))
+	for _, x := range nu {
+		for _, y := range de {
+			diffs = append(diffs, x-y)
+		}
+	}
+	sort.Float64s(diffs)
+	base := median(de)
+
+	// The normal approximation to the U distribution gives the
+	// rank of the lower bound. If the samples are too small for
+	// the requested confidence, use the full range.
+	n, m := float64(len(nu)), float64(len(de))
+	z := stats.StdNormal.InvCDF(1 - (1-confidence)/2)
+	k := int(math.Floor(n*m/2 - z*math.Sqrt(n*m*(n+m+1)/12)))
+	if k < 1 {
+		k = 1
+	}
+	center = relative(base+median(diffs), base)
+	low = relative(base+diffs[k-1], base)
+	high = relative(base+diffs[len(diffs)-k], base)
+	return
+}
+
+// MeanT is an Estimator of the ratio of the numerator mean to the
+// denominator mean, with the confidence interval of Welch's t-test for
+// the difference of the means made relative to the denominator mean.
+// It assumes the measurements are normally distributed.
+func MeanT(c *Comparison, confidence float64) (center, low, high float64) {
+	nu, de := c.Numerator.Values, c.Denominator.Values
+	mx, my := stats.Mean(nu), stats.Mean(de)
+	center = relative(mx, my)
+	n, m := float64(len(nu)), float64(len(de))
+	vx, vy := stats.Variance(nu)/n, stats.Variance(de)/m
+	se := math.Sqrt(vx + vy)
+	if len(nu) < 2 || len(de) < 2 || se == 0 {
+		return center, center, center
+	}
+	// Welch-Satterthwaite degrees of freedom.
+	dof := (vx + vy) * (vx + vy) / (vx*vx/(n-1) + vy*vy/(m-1))
+	t := stats.InvCDF(stats.TDist{V: dof})(1 - (1-confidence)/2)
+	low = relative(mx-t*se, my)
+	high = relative(mx+t*se, my)
+	return
+}
+
+// Exact is an Estimator for units whose measurements are exact, such
+// as binary sizes, that summarizes the ratio of the medians with an
+// empty confidence interval. It is always used for units with
+// assume=exact metadata.
+func Exact(c *Comparison, confidence float64) (center, low, high float64) {
+	center = relative(median(c.Numerator.Values), median(c.Denominator.Values))
+	return center, center, center
+}
+
+// relative returns the ratio num/den. If den is 0, it instead returns
+// num offset by 1 away from 0, so that a zero numerator is "no change".
+func relative(num, den float64) float64 {
+	if den != 0 {
+		return num / den
+	}
+	if num >= 0 {
+		return num + 1
+	}
+	return num - 1
+}
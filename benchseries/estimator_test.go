@@ -0,0 +1,101 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/perf/benchfmt"
+)
+
+func TestEstimators(t *testing.T) {
+	c := &Comparison{
+		Numerator:   &Cell{Values: []float64{11, 12, 13}},
+		Denominator: &Cell{Values: []float64{10, 10, 10}},
+	}
+	check := func(name string, est Estimator, wantCenter, wantLow, wantHigh float64) {
+		t.Helper()
+		center, low, high := est(c, 0.95)
+		if math.Abs(center-wantCenter) > 1e-3 || math.Abs(low-wantLow) > 1e-3 || math.Abs(high-wantHigh) > 1e-3 {
+			t.Errorf("%s: got %v [%v, %v], want %v [%v, %v]", name, center, low, high, wantCenter, wantLow, wantHigh)
+		}
+	}
+	// The samples are too small for a 95% interval, so
+	// Hodges-Lehmann uses the full range of shifts.
+	check("HodgesLehmann", HodgesLehmann, 1.2, 1.1, 1.3)
+	// t(0.975, 2 dof) = 4.303; se = sqrt(1/3).
+	check("MeanT", MeanT, 1.2, 0.9516, 1.4484)
+	check("Exact", Exact, 1.2, 1.2, 1.2)
+	check("Bootstrap", Bootstrap(100), 1.2, 1.1, 1.3)
+
+	// A zero denominator is treated as an offset from 1.
+	c.Denominator.Values = []float64{0, 0, 0}
+	check("Exact zero", Exact, 13, 13, 13)
+}
+
+func TestExactUnits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bench.txt")
+	const input = `runstamp: 2020-01-01T00:00:00Z
+numerator-hash: abcdef0123456789
+denominator-hash: 9876543219fedcba
+numerator-hash-time: 2020-02-02T00:00:00Z
+Unit B/op assume=exact
+compare: numerator
+BenchmarkFoo 10 11 sec/op 110 B/op
+BenchmarkFoo 10 12 sec/op 110 B/op
+BenchmarkFoo 10 13 sec/op 110 B/op
+compare: denominator
+BenchmarkFoo 10 10 sec/op 100 B/op
+BenchmarkFoo 10 10 sec/op 100 B/op
+BenchmarkFoo 10 10 sec/op 100 B/op
+`
+	if err := os.WriteFile(path, []byte(input), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	builder, err := NewBuilder(&BuilderOptions{
+		Filter:          ".unit:/.*/",
+		Series:          "numerator-hash-time",
+		Experiment:      "runstamp",
+		Compare:         "compare",
+		Numerator:       "numerator",
+		Denominator:     "denominator",
+		NumeratorHash:   "numerator-hash",
+		DenominatorHash: "denominator-hash",
+		Estimator:       MeanT,
+		Warn: func(format string, args ...interface{}) {
+			t.Errorf(format, args...)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := builder.AddFiles(benchfmt.Files{Paths: []string{path}}); err != nil {
+		t.Fatal(err)
+	}
+	css, err := builder.AllComparisonSeries(nil, DUPE_REPLACE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cs := range css {
+		cs.AddSummaries(0.95, 100)
+		sum := cs.Summaries[0][0]
+		switch cs.Unit {
+		case "B/op":
+			if sum.Low != 1.1 || sum.Center != 1.1 || sum.High != 1.1 {
+				t.Errorf("B/op: got %+v, want exact 1.1", sum)
+			}
+		case "sec/op":
+			if math.Abs(sum.Low-0.9516) > 1e-3 || math.Abs(sum.High-1.4484) > 1e-3 {
+				t.Errorf("sec/op: got %+v, want MeanT interval", sum)
+			}
+		default:
+			t.Errorf("unexpected unit %s", cs.Unit)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// GeomeanBenchmark is the name of the benchmark added by AddGeomean.
+const GeomeanBenchmark = "geomean"
+
+// AddGeomean adds a GeomeanBenchmark to cs, after all other benchmarks,
+// whose summary at each series point is the geometric mean of the
+// ratios of the other benchmarks there. Its confidence interval is
+// bootstrapped with N resamples of both the set of benchmarks and the
+// measurements of each benchmark. Benchmarks whose summaries have no
+// raw measurements contribute only their Center. Any existing geomean
+// is replaced. The geomean depends on which benchmarks are in cs, so
+// its summaries are marked Derived, and they are neither merged with
+// new results nor saved by a Store. AddSummaries must be called first.
+func (cs *ComparisonSeries) AddGeomean(confidence float64, N int) {
+	cs.removeGeomean()
+	for i, s := range cs.Series {
+		sum := cs.geomeanAt(i, confidence, N)
+		cs.Summaries[i] = append(cs.Summaries[i], sum)
+		if sum.Defined() {
+			c := &Comparison{Summary: sum, Date: sum.Date}
+			sum.comparison = c
+			cs.cells[SeriesKey{Benchmark: GeomeanBenchmark, Series: s}] = c
+		}
+	}
+	cs.Benchmarks = append(cs.Benchmarks, GeomeanBenchmark)
+}
+
+// removeGeomean removes any GeomeanBenchmark from cs.
+func (cs *ComparisonSeries) removeGeomean() {
+	if cs.cells == nil {
+		cs.cells = make(map[SeriesKey]*Comparison)
+	}
+	for j, b := range cs.Benchmarks {
+		if b != GeomeanBenchmark {
+			continue
+		}
+		cs.Benchmarks = append(cs.Benchmarks[:j:j], cs.Benchmarks[j+1:]...)
+		for i, s := range cs.Series {
+			cs.Summaries[i] = append(cs.Summaries[i][:j:j], cs.Summaries[i][j+1:]...)
+			delete(cs.cells, SeriesKey{Benchmark: b, Series: s})
+		}
+		return
+	}
+}
+
+// geomeanAt returns the geomean summary of series point i.
+func (cs *ComparisonSeries) geomeanAt(i int, confidence float64, N int) *ComparisonSummary {
+	type input struct {
+		center   float64
+		nu, de   *Cell     // nil if there are no raw measurements
+		rnu, rde []float64 // resampling buffers
+	}
+	var inputs []*input
+	var seed int64
+	date := ""
+	for _, sum := range cs.Summaries[i] {
+		if !sum.Defined() || !(sum.Center > 0) {
+			continue
+		}
+		in := &input{center: sum.Center}
+		if c := sum.comparison; c != nil && c.Numerator != nil && c.Denominator != nil && len(c.Numerator.Values) > 0 && len(c.Denominator.Values) > 0 {
+			in.nu, in.de = c.Numerator, c.Denominator
+		} else if len(sum.Numerator) > 0 && len(sum.Denominator) > 0 {
+			in.nu, in.de = &Cell{Values: sum.Numerator}, &Cell{Values: sum.Denominator}
+		}
+		if in.nu != nil {
+			in.rnu, in.rde = make([]float64, len(in.nu.Values)), make([]float64, len(in.de.Values))
+			seed ^= in.nu.hash() * in.de.hash()
+		}
+		inputs = append(inputs, in)
+		if sum.Date > date {
+			date = sum.Date
+		}
+	}
+	if len(inputs) == 0 {
+		return &ComparisonSummary{}
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	estimates := make([]float64, N)
+	for k := range estimates {
+		logSum, n := 0.0, 0
+		for range inputs {
+			in := inputs[r.Intn(len(inputs))]
+			ratio := in.center
+			if in.nu != nil {
+				in.nu.resampleInto(r, in.rnu)
+				in.de.resampleInto(r, in.rde)
+				ratio = relative(median(in.rnu), median(in.rde))
+			}
+			if ratio > 0 {
+				logSum += math.Log(ratio)
+				n++
+			}
+		}
+		if n == 0 {
+			estimates[k] = 1
+		} else {
+			estimates[k] = math.Exp(logSum / float64(n))
+		}
+	}
+	sort.Float64s(estimates)
+	p := (1 - confidence) / 2
+	return &ComparisonSummary{
+		Low:     percentile(estimates, p),
+		Center:  median(estimates),
+		High:    percentile(estimates, 1-p),
+		Date:    date,
+		Present: true,
+		Derived: true,
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import (
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddGeomean(t *testing.T) {
+	cs := seriesOf(0.01,
+		[]float64{1, 1.1, 1.1, 0},
+		[]float64{1, 1.21, 1.21, 0},
+	)
+	// Raw measurements are resampled when present.
+	cs.Summaries[2][0].Numerator = []float64{11, 11, 11}
+	cs.Summaries[2][0].Denominator = []float64{10, 10, 10}
+
+	cs.AddGeomean(0.95, 100)
+	// Adding it again replaces the existing geomean.
+	cs.AddGeomean(0.95, 100)
+	if len(cs.Benchmarks) != 3 || cs.Benchmarks[2] != GeomeanBenchmark || len(cs.Summaries[0]) != 3 {
+		t.Fatalf("got benchmarks %v", cs.Benchmarks)
+	}
+
+	for i, want := range []float64{1, math.Sqrt(1.1 * 1.21), math.Sqrt(1.1 * 1.21)} {
+		sum := cs.Summaries[i][2]
+		if !sum.Defined() || math.Abs(sum.Center-want) > 0.03 {
+			t.Errorf("point %d: got %+v, want center ~%v", i, sum, want)
+		}
+		// Resampling the benchmarks spans both ratios.
+		if i > 0 && (math.Abs(sum.Low-1.1) > 1e-9 || math.Abs(sum.High-1.21) > 1e-9) {
+			t.Errorf("point %d: got interval [%v, %v], want [1.1, 1.21]", i, sum.Low, sum.High)
+		}
+		if s, ok := cs.SummaryAt(GeomeanBenchmark, cs.Series[i]); !ok || s != sum {
+			t.Errorf("point %d: SummaryAt doesn't find geomean", i)
+		}
+	}
+	if cs.Summaries[3][2].Defined() {
+		t.Errorf("point 3: got %+v, want undefined", cs.Summaries[3][2])
+	}
+
+	// The geomean's change isn't a step change of its own.
+	for _, sc := range cs.StepChanges(0, 0, 0.02) {
+		for _, g := range sc.Groups {
+			for _, cp := range g.Changes {
+				if cp.Benchmark == GeomeanBenchmark {
+					t.Errorf("step changes include %s", GeomeanBenchmark)
+				}
+			}
+		}
+	}
+
+	// The measurements of new results are resampled even if they
+	// aren't kept in the summaries.
+	cs = seriesOf(0.01, []float64{1.1}, []float64{1.21})
+	cs.Summaries[0][0].comparison = &Comparison{Numerator: &Cell{Values: []float64{12, 12, 12}}, Denominator: &Cell{Values: []float64{10, 10, 10}}}
+	cs.AddGeomean(0.95, 100)
+	if sum := cs.Summaries[0][2]; math.Abs(sum.Low-1.2) > 1e-9 || math.Abs(sum.High-1.21) > 1e-9 {
+		t.Errorf("got interval [%v, %v], want [1.2, 1.21]", sum.Low, sum.High)
+	}
+}
+
+func TestGeomeanDerived(t *testing.T) {
+	cs := seriesOf(0.01, []float64{1, 1.1}, []float64{1, 1.21})
+	cs.AddGeomean(0.95, 100)
+
+	// The geomean is in the JSON output, marked as derived.
+	data, err := json.Marshal([]*ComparisonSeries{cs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"geomean"`) || !strings.Contains(string(data), `"derived":true`) {
+		t.Errorf("JSON output missing derived geomean: %s", data)
+	}
+
+	// Reading it back, it isn't merged with new results.
+	var got []*ComparisonSeries
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	benches, sers := make(map[string]struct{}), make(map[string]struct{})
+	got[0].loadCells(benches, sers)
+	if _, ok := benches[GeomeanBenchmark]; ok || len(benches) != 2 {
+		t.Errorf("merged benchmarks %v, want B0 and B1", benches)
+	}
+
+	// Nor is it stored.
+	s, err := OpenSQLiteStore(filepath.Join(t.TempDir(), "series.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Put([]*ComparisonSeries{cs}); err != nil {
+		t.Fatal(err)
+	}
+	got, err = s.Get(StoreQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || len(got[0].Benchmarks) != 2 {
+		data, _ := json.Marshal(got)
+		t.Errorf("got %s, want only B0 and B1", data)
+	}
+}
@@ -0,0 +1,322 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import (
+	"html/template"
+	"io"
+	"math"
+)
+
+// htmlTable is the data for one ComparisonSeries in the HTML
+// dashboard. It's encoded as JSON for the page's script.
+type htmlTable struct {
+	Unit       string          `json:"unit"`
+	Better     int             `json:"better"`
+	Series     []string        `json:"series"`
+	NumHashes  []string        `json:"numHashes"`
+	DenHashes  []string        `json:"denHashes"`
+	Benchmarks []htmlBenchmark `json:"benchmarks"`
+}
+
+type htmlBenchmark struct {
+	Name    string        `json:"name"`
+	Points  []htmlPoint   `json:"points"`
+	Changes []ChangePoint `json:"changes"`
+}
+
+type htmlPoint struct {
+	I      int     `json:"i"` // Index into Series
+	Low    float64 `json:"low"`
+	Center float64 `json:"center"`
+	High   float64 `json:"high"`
+}
+
+// WriteHTML writes an HTML dashboard of cs to w. The dashboard shows a
+// time-series chart of each benchmark's comparison ratio with its
+// confidence band and the change points found by ChangePoints(penalty).
+// Change points are coloured as regressions or improvements according
+// to each series' Better direction. Charts can be zoomed with the mouse
+// wheel, panned by dragging, and reset by double-clicking, and hovering
+// over a point shows its date and the hashes compared. Each table can
+// be filtered by benchmark name. The output is a single self-contained
+// file that doesn't load any external resources. AddSummaries must be
+// called first.
+func WriteHTML(w io.Writer, cs []*ComparisonSeries, penalty float64) error {
+	var tables []htmlTable
+	for _, c := range cs {
+		t := htmlTable{Unit: c.Unit, Better: c.Better, Series: c.Series}
+		for _, s := range c.Series {
+			hp := c.HashPairs[s]
+			t.NumHashes = append(t.NumHashes, hp.NumHash)
+			t.DenHashes = append(t.DenHashes, hp.DenHash)
+		}
+		changes := c.ChangePoints(penalty)
+		for j, b := range c.Benchmarks {
+			hb := htmlBenchmark{Name: b, Points: []htmlPoint{}, Changes: changes[j]}
+			for i := range c.Series {
+				sum := c.Summaries[i][j]
+				if !sum.Defined() || !finite(sum.Low) || !finite(sum.Center) || !finite(sum.High) {
+					continue
+				}
+				hb.Points = append(hb.Points, htmlPoint{i, sum.Low, sum.Center, sum.High})
+			}
+			if hb.Changes == nil {
+				hb.Changes = []ChangePoint{}
+			}
+			t.Benchmarks = append(t.Benchmarks, hb)
+		}
+		tables = append(tables, t)
+	}
+	return htmlDashboard.Execute(w, tables)
+}
+
+func finite(x float64) bool {
+	return !math.IsInf(x, 0) && !math.IsNaN(x)
+}
+
+var htmlDashboard = template.Must(template.New("").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>benchseries</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+h2 { font-size: 1.2em; margin-bottom: 0.3em; }
+.filter { margin-bottom: 0.5em; width: 30em; }
+.chart { display: inline-block; margin: 0 1em 1em 0; vertical-align: top; }
+.chart .name { font-size: 0.85em; font-family: monospace; }
+svg { border: 1px solid #ccc; cursor: grab; user-select: none; }
+.band { fill: #4a90d9; fill-opacity: 0.25; stroke: none; }
+.center { fill: none; stroke: #1a5fa8; stroke-width: 1.5; }
+.point { fill: #1a5fa8; }
+.point:hover { fill: #e07000; }
+.one { stroke: #999; stroke-dasharray: 2 2; }
+.change { stroke-width: 1.5; stroke-dasharray: 4 2; }
+.worse { stroke: #c00; }
+.better { stroke: #080; }
+.up { stroke: #a0a; }
+.down { stroke: #08a; }
+.axis { font-size: 10px; fill: #555; }
+#tip { position: fixed; display: none; background: #fff; border: 1px solid #888; padding: 0.3em 0.5em; font-size: 0.8em; font-family: monospace; white-space: pre; pointer-events: none; }
+</style>
+</head>
+<body>
+<p>Scroll to zoom, drag to pan, and double-click to reset. Dashed vertical lines mark change points: red for regressions and green for improvements, or purple and blue for increases and decreases in units with no known better direction.</p>
+<div id="tables"></div>
+<div id="tip"></div>
+<script>
+"use strict";
+const tables = {{.}} || [];
+const W = 480, H = 160, ML = 48, MR = 8, MT = 8, MB = 20;
+const svgNS = "http://www.w3.org/2000/svg";
+const tip = document.getElementById("tip");
+
+function el(name, attrs, parent) {
+	const e = document.createElementNS(svgNS, name);
+	for (const k in attrs) e.setAttribute(k, attrs[k]);
+	if (parent) parent.appendChild(e);
+	return e;
+}
+
+function text(parent, x, y, s, anchor) {
+	const t = el("text", {x: x, y: y, "class": "axis", "text-anchor": anchor || "start"}, parent);
+	t.textContent = s;
+	return t;
+}
+
+function fmt(x) {
+	return x.toPrecision(4);
+}
+
+function drawChart(table, b, view, parent) {
+	const svg = el("svg", {width: W, height: H}, null);
+	const pts = b.points.filter(p => p.i >= view.lo && p.i <= view.hi);
+	const span = Math.max(view.hi - view.lo, 1);
+	const x = i => ML + (i - view.lo) / span * (W - ML - MR);
+	let ymin = Infinity, ymax = -Infinity;
+	for (const p of pts) {
+		ymin = Math.min(ymin, p.low);
+		ymax = Math.max(ymax, p.high);
+	}
+	if (!isFinite(ymin)) {
+		ymin = 0.9;
+		ymax = 1.1;
+	}
+	if (ymax - ymin < 1e-9) {
+		ymin -= 0.01;
+		ymax += 0.01;
+	}
+	const pad = (ymax - ymin) * 0.05;
+	ymin -= pad;
+	ymax += pad;
+	const y = v => MT + (ymax - v) / (ymax - ymin) * (H - MT - MB);
+
+	text(svg, ML - 4, MT + 8, fmt(ymax), "end");
+	text(svg, ML - 4, H - MB, fmt(ymin), "end");
+	text(svg, ML, H - 4, (table.series[view.lo] || "").slice(0, 10));
+	text(svg, W - MR, H - 4, (table.series[view.hi] || "").slice(0, 10), "end");
+	if (ymin < 1 && ymax > 1) {
+		el("line", {x1: ML, x2: W - MR, y1: y(1), y2: y(1), "class": "one"}, svg);
+	}
+
+	if (pts.length > 0) {
+		let band = "M" + pts.map(p => x(p.i) + "," + y(p.high)).join("L");
+		band += "L" + pts.slice().reverse().map(p => x(p.i) + "," + y(p.low)).join("L") + "Z";
+		el("path", {d: band, "class": "band"}, svg);
+		el("polyline", {points: pts.map(p => x(p.i) + "," + y(p.center)).join(" "), "class": "center"}, svg);
+	}
+
+	for (const c of b.changes) {
+		if (c.index < view.lo || c.index > view.hi) continue;
+		// Put the marker between the last old point and the first new one.
+		const cx = (x(c.prev) + x(c.index)) / 2;
+		const line = el("line", {x1: cx, x2: cx, y1: MT, y2: H - MB, "class": "change " + direction(table, c)}, svg);
+		line.addEventListener("mousemove", ev => showTip(ev,
+			"change " + (c.magnitude > 0 ? "+" : "") + (c.magnitude * 100).toFixed(2) + "%\n" +
+			"confidence " + c.confidence.toFixed(3) + "\n" +
+			table.numHashes[c.prev] + ".." + table.numHashes[c.index]));
+		line.addEventListener("mouseleave", hideTip);
+	}
+
+	for (const p of pts) {
+		const circle = el("circle", {cx: x(p.i), cy: y(p.center), r: 2.5, "class": "point"}, svg);
+		circle.addEventListener("mousemove", ev => showTip(ev,
+			table.series[p.i] + "\n" +
+			"numerator   " + table.numHashes[p.i] + "\n" +
+			"denominator " + table.denHashes[p.i] + "\n" +
+			fmt(p.center) + " [" + fmt(p.low) + ", " + fmt(p.high) + "]"));
+		circle.addEventListener("mouseleave", hideTip);
+	}
+
+	const div = document.createElement("div");
+	div.className = "chart";
+	const name = document.createElement("div");
+	name.className = "name";
+	name.textContent = b.name;
+	div.appendChild(name);
+	div.appendChild(svg);
+	parent.appendChild(div);
+	return svg;
+}
+
+// direction returns the marker class for change c: worse or better if
+// the unit's better direction is known, and otherwise up or down.
+function direction(table, c) {
+	if (table.better === 0) {
+		return c.magnitude > 0 ? "up" : "down";
+	}
+	return c.magnitude * table.better > 0 ? "better" : "worse";
+}
+
+function showTip(ev, s) {
+	tip.textContent = s;
+	tip.style.display = "block";
+	tip.style.left = (ev.clientX + 12) + "px";
+	tip.style.top = (ev.clientY + 12) + "px";
+}
+
+function hideTip() {
+	tip.style.display = "none";
+}
+
+function drawTable(table, root) {
+	const section = document.createElement("section");
+	const h = document.createElement("h2");
+	h.textContent = table.unit;
+	section.appendChild(h);
+	const filter = document.createElement("input");
+	filter.className = "filter";
+	filter.placeholder = "Filter benchmarks (regexp)";
+	section.appendChild(filter);
+	const charts = document.createElement("div");
+	section.appendChild(charts);
+	root.appendChild(section);
+
+	const last = Math.max(table.series.length - 1, 0);
+	const view = {lo: 0, hi: last};
+	let re = null;
+
+	function render() {
+		charts.textContent = "";
+		for (const b of table.benchmarks) {
+			if (re && !re.test(b.name)) continue;
+			const svg = drawChart(table, b, view, charts);
+			attach(svg);
+		}
+	}
+
+	// Convert a client x coordinate to a fractional series index.
+	function index(svg, clientX) {
+		const r = svg.getBoundingClientRect();
+		const f = (clientX - r.left - ML) / (W - ML - MR);
+		return view.lo + Math.min(Math.max(f, 0), 1) * (view.hi - view.lo);
+	}
+
+	function setView(lo, hi) {
+		if (hi - lo < 1) {
+			return;
+		}
+		if (lo < 0) {
+			hi -= lo;
+			lo = 0;
+		}
+		if (hi > last) {
+			lo -= hi - last;
+			hi = last;
+		}
+		view.lo = Math.max(0, Math.round(lo));
+		view.hi = Math.min(last, Math.round(hi));
+		render();
+	}
+
+	function attach(svg) {
+		svg.addEventListener("wheel", ev => {
+			ev.preventDefault();
+			const at = index(svg, ev.clientX);
+			const k = ev.deltaY < 0 ? 0.8 : 1.25;
+			setView(at - (at - view.lo) * k, at + (view.hi - at) * k);
+		});
+		svg.addEventListener("dblclick", () => setView(0, last));
+		svg.addEventListener("mousedown", ev => {
+			// Charts are redrawn while panning, so work from the
+			// starting view and mouse position.
+			const startX = ev.clientX, lo = view.lo, hi = view.hi;
+			const move = ev2 => {
+				const d = (startX - ev2.clientX) / (W - ML - MR) * (hi - lo);
+				if (Math.round(lo + d) !== view.lo) {
+					setView(lo + d, hi + d);
+				}
+			};
+			const up = () => {
+				window.removeEventListener("mousemove", move);
+				window.removeEventListener("mouseup", up);
+			};
+			window.addEventListener("mousemove", move);
+			window.addEventListener("mouseup", up);
+		});
+	}
+
+	filter.addEventListener("input", () => {
+		try {
+			re = filter.value ? new RegExp(filter.value) : null;
+			filter.style.color = "";
+		} catch (e) {
+			filter.style.color = "red";
+			return;
+		}
+		render();
+	});
+	render();
+}
+
+const root = document.getElementById("tables");
+for (const t of tables) {
+	drawTable(t, root);
+}
+</script>
+</body>
+</html>
+`))
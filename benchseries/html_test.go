@@ -0,0 +1,51 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestWriteHTML(t *testing.T) {
+	cs := seriesOf(0.01,
+		[]float64{1, 1, 1, 1, 1.3, 1.3, 1.3, 1.3},
+		[]float64{1, 1, 0, 1, 1, 1, 1, 1},
+	)
+	cs.Benchmarks[1] = "</script><b>"
+	cs.Better = -1
+	// Non-finite summaries are omitted, since they can't be
+	// encoded in JSON.
+	cs.Summaries[1][1].High = math.Inf(1)
+
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, []*ComparisonSeries{cs}, 0); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+
+	for _, want := range []string{
+		`"unit":"sec/op","better":-1`,
+		`"numHashes":["h00","h01",`,
+		`"changes":[{"benchmark":"B0","series":"2022-01-05T00:00:00+00:00","index":4,"prev":3,`,
+		`"points":[{"i":0,"low":0.99,"center":1,"high":1.01},{"i":3,`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %s", want)
+		}
+	}
+	// Benchmark names are escaped.
+	if strings.Contains(got, "</script><b>") {
+		t.Errorf("benchmark name not escaped")
+	}
+	// The page is self-contained.
+	for _, bad := range []string{"src=", "href=", "@import", "url("} {
+		if strings.Contains(got, bad) {
+			t.Errorf("output contains %s", bad)
+		}
+	}
+}
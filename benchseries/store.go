@@ -0,0 +1,372 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package benchseries
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A Store is persistent storage for comparison series. Unlike a JSON
+// summary, which must be read and rewritten in its entirety, a Store
+// can be updated incrementally and queried for just the data needed.
+type Store interface {
+	// Put adds the summaries in cs to the store, replacing any
+	// existing summaries for the same unit, benchmark, and series
+	// point. Summaries that were read from the store and not
+	// changed since are not rewritten. It is an error for a summary
+	// of new results that was not merged with an existing summary
+	// to replace a stored one, as happens if the existing summaries
+	// were read with a query that excluded it. Derived summaries are
+	// not stored. AddSummaries must be called first.
+	Put(cs []*ComparisonSeries) error
+
+	// Get returns the comparison series matching q, one per unit,
+	// in unit order.
+	Get(q StoreQuery) ([]*ComparisonSeries, error)
+
+	// Close releases the resources held by the store.
+	Close() error
+}
+
+// A StoreQuery selects summaries from a Store. The zero value
+// selects everything.
+type StoreQuery struct {
+	Unit      string // If non-empty, only this unit.
+	Benchmark string // If non-empty, only this benchmark.
+
+	// Since and Until, if non-empty, are the first and last series
+	// points (inclusive) to return. They are normalized with
+	// NormalizeDateString.
+	Since, Until string
+}
+
+// SQLiteStore is a Store backed by an SQLite database.
+type SQLiteStore struct {
+	sql *sql.DB
+}
+
+var sqliteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS Summaries (
+		Unit TEXT NOT NULL,
+		Benchmark TEXT NOT NULL,
+		Series TEXT NOT NULL,
+		Low REAL NOT NULL,
+		Center REAL NOT NULL,
+		High REAL NOT NULL,
+		Date TEXT NOT NULL,
+		Numerator TEXT NOT NULL,
+		Denominator TEXT NOT NULL,
+		PRIMARY KEY (Unit, Benchmark, Series)
+	)`,
+	`CREATE INDEX IF NOT EXISTS SummariesSeries ON Summaries(Unit, Series)`,
+	`CREATE TABLE IF NOT EXISTS HashPairs (
+		Unit TEXT NOT NULL,
+		Series TEXT NOT NULL,
+		NumHash TEXT NOT NULL,
+		DenHash TEXT NOT NULL,
+		PRIMARY KEY (Unit, Series)
+	)`,
+	`CREATE TABLE IF NOT EXISTS Units (
+		Unit TEXT NOT NULL PRIMARY KEY,
+		Better INTEGER NOT NULL,
+		Exact BOOLEAN NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS Residues (
+		Unit TEXT NOT NULL,
+		Name TEXT NOT NULL,
+		Value TEXT NOT NULL,
+		PRIMARY KEY (Unit, Name, Value)
+	)`,
+	`CREATE TABLE IF NOT EXISTS Acknowledgements (
+		Unit TEXT NOT NULL,
+		Benchmark TEXT NOT NULL,
+		Series TEXT NOT NULL,
+		Until TEXT NOT NULL,
+		PRIMARY KEY (Unit, Benchmark, Series, Until)
+	)`,
+}
+
+// OpenSQLiteStore opens the SQLite database named by dataSourceName,
+// creating the tables it needs if they don't exist. The caller must
+// link in the "sqlite3" driver, for example by importing
+// golang.org/x/perf/storage/db/sqlite3.
+func OpenSQLiteStore(dataSourceName string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range sqliteSchema {
+		if _, err := db.Exec(q); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("creating tables: %v", err)
+		}
+	}
+	return &SQLiteStore{db}, nil
+}
+
+// Close closes the database.
+func (s *SQLiteStore) Close() error {
+	return s.sql.Close()
+}
+
+// Put adds the summaries in cs to the database in a single transaction.
+func (s *SQLiteStore) Put(cs []*ComparisonSeries) (err error) {
+	tx, err := s.sql.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	insertSummary, err := tx.Prepare("INSERT OR REPLACE INTO Summaries(Unit, Benchmark, Series, Low, Center, High, Date, Numerator, Denominator) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer insertSummary.Close()
+	addSummary, err := tx.Prepare("INSERT OR IGNORE INTO Summaries(Unit, Benchmark, Series, Low, Center, High, Date, Numerator, Denominator) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer addSummary.Close()
+	insertHashes, err := tx.Prepare("INSERT OR REPLACE INTO HashPairs(Unit, Series, NumHash, DenHash) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer insertHashes.Close()
+	insertResidue, err := tx.Prepare("INSERT OR IGNORE INTO Residues(Unit, Name, Value) VALUES (?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer insertResidue.Close()
+	insertUnit, err := tx.Prepare("INSERT OR REPLACE INTO Units(Unit, Better, Exact) VALUES (?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer insertUnit.Close()
+	insertAck, err := tx.Prepare("INSERT OR IGNORE INTO Acknowledgements(Unit, Benchmark, Series, Until) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer insertAck.Close()
+
+	for _, c := range cs {
+		if _, err := insertUnit.Exec(c.Unit, c.Better, c.Exact); err != nil {
+			return err
+		}
+		for i, ser := range c.Series {
+			wrote := false
+			for j, b := range c.Benchmarks {
+				sum := c.Summaries[i][j]
+				if !sum.Defined() || sum.stored || sum.Derived {
+					continue
+				}
+				num, err := json.Marshal(sum.Numerator)
+				if err != nil {
+					return err
+				}
+				den, err := json.Marshal(sum.Denominator)
+				if err != nil {
+					return err
+				}
+				// New results that weren't merged with an existing
+				// summary must not silently replace a stored one.
+				stmt := insertSummary
+				if cc := sum.comparison; cc != nil && !cc.existing && !cc.merged {
+					stmt = addSummary
+				}
+				r, err := stmt.Exec(c.Unit, b, ser, sum.Low, sum.Center, sum.High, sum.Date, string(num), string(den))
+				if err != nil {
+					return fmt.Errorf("%s: storing %s at %s: %v", c.Unit, b, ser, err)
+				}
+				if n, err := r.RowsAffected(); err != nil {
+					return err
+				} else if n == 0 {
+					return fmt.Errorf("%s: storing %s at %s: already stored, but not read to merge with new results", c.Unit, b, ser)
+				}
+				wrote = true
+			}
+			if hp, ok := c.HashPairs[ser]; ok && wrote {
+				if _, err := insertHashes.Exec(c.Unit, ser, hp.NumHash, hp.DenHash); err != nil {
+					return err
+				}
+			}
+		}
+		for _, r := range c.Residues {
+			for _, v := range r.Slice {
+				if _, err := insertResidue.Exec(c.Unit, r.S, v); err != nil {
+					return err
+				}
+			}
+		}
+		for _, a := range c.Acknowledged {
+			if _, err := insertAck.Exec(c.Unit, a.Benchmark, a.Series, a.Until); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Get returns the comparison series matching q. The summaries are
+// marked as stored, so passing them back to Put is cheap.
+func (s *SQLiteStore) Get(q StoreQuery) ([]*ComparisonSeries, error) {
+	var where []string
+	var args []interface{}
+	if q.Unit != "" {
+		where = append(where, "s.Unit = ?")
+		args = append(args, q.Unit)
+	}
+	if q.Benchmark != "" {
+		where = append(where, "s.Benchmark = ?")
+		args = append(args, q.Benchmark)
+	}
+	for _, bound := range []struct{ date, op string }{{q.Since, ">="}, {q.Until, "<="}} {
+		if bound.date == "" {
+			continue
+		}
+		d, err := NormalizeDateString(bound.date)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, "s.Series "+bound.op+" ?")
+		args = append(args, d)
+	}
+	query := "SELECT s.Unit, s.Benchmark, s.Series, s.Low, s.Center, s.High, s.Date, s.Numerator, s.Denominator, h.NumHash, h.DenHash FROM Summaries s LEFT JOIN HashPairs h ON s.Unit = h.Unit AND s.Series = h.Series"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY s.Unit"
+
+	rows, err := s.sql.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type partial struct {
+		cs      *ComparisonSeries
+		benches map[string]struct{}
+		sers    map[string]struct{}
+		sums    map[SeriesKey]*ComparisonSummary
+	}
+	var parts []*partial
+	var p *partial
+	for rows.Next() {
+		var unit, bench, ser, num, den string
+		var numHash, denHash sql.NullString
+		sum := &ComparisonSummary{Present: true, stored: true}
+		if err := rows.Scan(&unit, &bench, &ser, &sum.Low, &sum.Center, &sum.High, &sum.Date, &num, &den, &numHash, &denHash); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(num), &sum.Numerator); err != nil {
+			return nil, fmt.Errorf("%s: %s at %s: bad numerator: %v", unit, bench, ser, err)
+		}
+		if err := json.Unmarshal([]byte(den), &sum.Denominator); err != nil {
+			return nil, fmt.Errorf("%s: %s at %s: bad denominator: %v", unit, bench, ser, err)
+		}
+		if p == nil || p.cs.Unit != unit {
+			p = &partial{
+				cs:      &ComparisonSeries{Unit: unit, HashPairs: make(map[string]ComparisonHashes)},
+				benches: make(map[string]struct{}),
+				sers:    make(map[string]struct{}),
+				sums:    make(map[SeriesKey]*ComparisonSummary),
+			}
+			parts = append(parts, p)
+		}
+		p.benches[bench] = struct{}{}
+		p.sers[ser] = struct{}{}
+		p.sums[SeriesKey{Benchmark: bench, Series: ser}] = sum
+		if numHash.Valid && denHash.Valid {
+			p.cs.HashPairs[ser] = ComparisonHashes{NumHash: numHash.String, DenHash: denHash.String}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var css []*ComparisonSeries
+	for _, p := range parts {
+		cs := p.cs
+		cs.Benchmarks = sortStringSet(p.benches)
+		cs.Series = sortStringSet(p.sers)
+		for _, ser := range cs.Series {
+			var row []*ComparisonSummary
+			for _, b := range cs.Benchmarks {
+				sum := p.sums[SeriesKey{Benchmark: b, Series: ser}]
+				if sum == nil {
+					sum = &ComparisonSummary{}
+				}
+				row = append(row, sum)
+			}
+			cs.Summaries = append(cs.Summaries, row)
+		}
+		if cs.Residues, err = s.residues(cs.Unit); err != nil {
+			return nil, err
+		}
+		if cs.Acknowledged, err = s.acknowledgements(cs.Unit); err != nil {
+			return nil, err
+		}
+		err := s.sql.QueryRow("SELECT Better, Exact FROM Units WHERE Unit = ?", cs.Unit).Scan(&cs.Better, &cs.Exact)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		css = append(css, cs)
+	}
+	return css, nil
+}
+
+// residues returns the residues recorded for unit.
+func (s *SQLiteStore) residues(unit string) ([]StringAndSlice, error) {
+	rows, err := s.sql.Query("SELECT Name, Value FROM Residues WHERE Unit = ?", unit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	m := make(map[string][]string)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+		m[name] = append(m[name], value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sas := []StringAndSlice{}
+	for k, v := range m {
+		sort.Strings(v)
+		sas = append(sas, StringAndSlice{k, v})
+	}
+	sort.Slice(sas, func(i, j int) bool { return sas[i].S < sas[j].S })
+	return sas, nil
+}
+
+// acknowledgements returns the acknowledgements recorded for unit.
+func (s *SQLiteStore) acknowledgements(unit string) ([]Acknowledgement, error) {
+	rows, err := s.sql.Query("SELECT Benchmark, Series, Until FROM Acknowledgements WHERE Unit = ? ORDER BY Benchmark, Series, Until", unit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var acks []Acknowledgement
+	for rows.Next() {
+		var a Acknowledgement
+		if err := rows.Scan(&a.Benchmark, &a.Series, &a.Until); err != nil {
+			return nil, err
+		}
+		acks = append(acks, a)
+	}
+	return acks, rows.Err()
+}
@@ -0,0 +1,174 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build cgo
+
+package benchseries
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/perf/benchfmt"
+	_ "golang.org/x/perf/storage/db/sqlite3"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	s, err := OpenSQLiteStore(filepath.Join(t.TempDir(), "series.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	cs := seriesOf(0.01,
+		[]float64{1, 1, 1.2, 1.2},
+		[]float64{1, 0, 1, 1},
+	)
+	cs.Residues = []StringAndSlice{{"goarch", []string{"amd64"}}}
+	cs.Better, cs.Exact = -1, true
+	cs.Acknowledge(Acknowledgement{Benchmark: "B0", Series: cs.Series[2]})
+	cs.Summaries[0][0].Numerator = []float64{2, 3}
+	cs.Summaries[0][0].Denominator = []float64{2, 2}
+	if err := s.Put([]*ComparisonSeries{cs}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A full query round-trips through JSON unchanged.
+	got, err := s.Get(StoreQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jsonOf := func(v interface{}) string {
+		var buf bytes.Buffer
+		if err := json.NewEncoder(&buf).Encode(v); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	if g, w := jsonOf(got), jsonOf([]*ComparisonSeries{cs}); g != w {
+		t.Errorf("got %s\nwant %s", g, w)
+	}
+
+	// Incremental upsert: change one summary and add a new point.
+	// Only the changed summaries are written.
+	old := got[0]
+	old.Summaries[3][1] = &ComparisonSummary{Low: 2, Center: 2, High: 2, Date: "2022-02-01T00:00:00+00:00", Present: true}
+	if err := s.Put(got); err != nil {
+		t.Fatal(err)
+	}
+	extra := seriesOf(0.01, []float64{1, 1, 1, 1, 1.5})
+	extra.Summaries = extra.Summaries[4:]
+	extra.Series = extra.Series[4:]
+	if err := s.Put([]*ComparisonSeries{extra}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = s.Get(StoreQuery{Unit: "sec/op", Benchmark: "B1", Since: "2022-01-04T00:00:00Z", Until: "2022-01-05T00:00:00Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || len(got[0].Benchmarks) != 1 || len(got[0].Series) != 1 {
+		t.Fatalf("got %s", jsonOf(got))
+	}
+	if sum := got[0].Summaries[0][0]; sum.Center != 2 || got[0].Series[0] != "2022-01-04T00:00:00+00:00" {
+		t.Errorf("got %+v at %s, want center 2", sum, got[0].Series[0])
+	}
+
+	got, err = s.Get(StoreQuery{Since: "2022-01-05T00:00:00Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || len(got[0].Series) != 1 || got[0].Summaries[0][0].Center != 1.5 || got[0].HashPairs[got[0].Series[0]].NumHash != "h04" {
+		t.Errorf("got %s", jsonOf(got))
+	}
+
+	if _, err := s.Get(StoreQuery{Since: "not a date"}); err == nil {
+		t.Errorf("bad date: want error")
+	}
+}
+
+func TestSQLiteStoreFiltered(t *testing.T) {
+	s, err := OpenSQLiteStore(filepath.Join(t.TempDir(), "series.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// build merges an experiment at runstamp measuring B0 and B1 at
+	// the same series point with the summaries in the store matching
+	// q, and puts the result back.
+	build := func(q StoreQuery, dupeHow int, runstamp string) error {
+		builder, err := NewBuilder(&BuilderOptions{
+			Filter:          ".unit:/.*/",
+			Series:          "numerator-hash-time",
+			Experiment:      "runstamp",
+			Compare:         "compare",
+			Numerator:       "numerator",
+			Denominator:     "denominator",
+			NumeratorHash:   "numerator-hash",
+			DenominatorHash: "denominator-hash",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"B0", "B1"} {
+			for _, compare := range []string{"numerator", "denominator"} {
+				builder.Add(&benchfmt.Result{
+					Config: []benchfmt.Config{
+						makeConfig("compare", compare),
+						makeConfig("runstamp", runstamp),
+						makeConfig("numerator-hash", "abcdef0123456789"),
+						makeConfig("denominator-hash", "9876543219fedcba"),
+						makeConfig("numerator-hash-time", "2020-02-02T00:00:00Z"),
+					},
+					Name:   []byte(name),
+					Iters:  10,
+					Values: []benchfmt.Value{{Value: 10, Unit: "sec"}},
+				})
+			}
+		}
+		existing, err := s.Get(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		css, err := builder.AllComparisonSeries(existing, dupeHow)
+		if err != nil {
+			return err
+		}
+		for _, cs := range css {
+			cs.AddSummaries(0.95, 100)
+		}
+		return s.Put(css)
+	}
+
+	if err := build(StoreQuery{}, DUPE_ERROR, "2020-01-01T00:00:00Z"); err != nil {
+		t.Fatal(err)
+	}
+	if err := build(StoreQuery{}, DUPE_ERROR, "2020-01-02T00:00:00Z"); err == nil {
+		t.Errorf("full query: want error for overlapping results")
+	}
+	if err := build(StoreQuery{}, DUPE_REPLACE, "2020-01-02T00:00:00Z"); err != nil {
+		t.Errorf("full query: %v", err)
+	}
+	// The overlap is not read, so it can't be merged, and Put must
+	// not replace the stored summaries.
+	for _, q := range []StoreQuery{{Benchmark: "B0"}, {Since: "2020-03-01T00:00:00Z"}, {Unit: "B/op"}} {
+		if err := build(q, DUPE_ERROR, "2020-01-03T00:00:00Z"); err == nil {
+			t.Errorf("%+v: want error for overlapping results", q)
+		}
+	}
+	got, err := s.Get(StoreQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range got[0].Summaries {
+		for _, sum := range row {
+			if sum.Date != "2020-01-02T00:00:00+00:00" {
+				t.Errorf("got summary dated %s, want 2020-01-02", sum.Date)
+			}
+		}
+	}
+}
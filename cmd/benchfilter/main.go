@@ -6,6 +6,22 @@
 // them, and writes filtered benchmark results to stdout. If no inputs
 // are provided, it reads from stdin.
 //
+// The -derive flag adds measurements computed from other measurements
+// before filtering, such as -derive 'ns/elem=sec/op / /size'. The
+// derived unit syntax is described at
+// https://pkg.go.dev/golang.org/x/perf/benchproc/syntax#hdr-Derived_units
+//
+// The -name-schema flag assigns keys to sub-benchmark name parts that
+// aren't in key=value form, such as -name-schema /size/codec, so the
+// query and -derive flags can use them. Results are written with their
+// original names. The name schema syntax is described at
+// https://pkg.go.dev/golang.org/x/perf/benchproc/syntax#hdr-Name_schemas
+//
+// The -explain flag prints, instead of the filtered results, each
+// input result followed by a tree showing which parts of the query
+// matched it. This is useful for finding out why a query drops results
+// unexpectedly.
+//
 // The filter language is described at
 // https://pkg.go.dev/golang.org/x/perf/cmd/benchstat#Filtering
 package main
@@ -21,7 +37,7 @@ import (
 )
 
 func usage() {
-	fmt.Fprintf(flag.CommandLine.Output(), `Usage: benchfilter query [inputs...]
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: benchfilter [flags] query [inputs...]
 
 benchfilter reads Go benchmark results from input files, filters them,
 and writes filtered benchmark results to stdout. If no inputs are
@@ -37,6 +53,10 @@ func main() {
 	log.SetPrefix("")
 	log.SetFlags(0)
 
+	flagExplain := flag.Bool("explain", false, "instead of filtering, print which parts of the query match each result")
+	flagNameSchema := flag.String("name-schema", "", "assign keys to positional sub-benchmark name parts using `schema`, such as /size/codec")
+	var derive benchproc.Derivations
+	flag.Var(&derive, "derive", "add a measurement derived from other measurements by `unit=expr`; may be repeated")
 	flag.Usage = usage
 	flag.Parse()
 	if flag.NArg() < 1 {
@@ -44,9 +64,20 @@ func main() {
 		os.Exit(2)
 	}
 
-	// TODO: Consider adding filtering on values, like "@ns/op>=100".
+	var nameSchema *benchproc.NameSchema
+	if *flagNameSchema != "" {
+		var err error
+		nameSchema, err = benchproc.NewNameSchema(*flagNameSchema)
+		if err != nil {
+			log.Fatal("parsing -name-schema: ", err)
+		}
+		derive, err = derive.WithNameSchema(nameSchema)
+		if err != nil {
+			log.Fatal("parsing -derive: ", err)
+		}
+	}
 
-	filter, err := benchproc.NewFilter(flag.Arg(0))
+	filter, err := nameSchema.NewFilter(flag.Arg(0))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -62,6 +93,12 @@ func main() {
 			fmt.Fprintln(os.Stderr, rec)
 			continue
 		case *benchfmt.Result:
+			derive.Apply(rec)
+			if *flagExplain {
+				fileName, line := rec.Pos()
+				fmt.Printf("%s:%d: %s\n%s", fileName, line, rec.Name.Full(), filter.Explain(rec))
+				continue
+			}
 			if ok, err := filter.Apply(rec); !ok {
 				if err != nil {
 					// Print the reason we rejected this result.
@@ -71,6 +108,9 @@ func main() {
 			}
 		}
 
+		if *flagExplain {
+			continue
+		}
 		err = writer.Write(rec)
 		if err != nil {
 			log.Fatal("writing output: ", err)
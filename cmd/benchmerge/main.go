@@ -0,0 +1,100 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// benchmerge reads Go benchmark results from input files, merges
+// them, and writes them to stdout ordered by file configuration and
+// benchmark name. If no inputs are provided, it reads from stdin.
+//
+// Results with identical file configuration are grouped together, so
+// each distinct configuration is written only once. With -dedup,
+// benchmerge also drops results that are identical to another result,
+// which is useful for combining overlapping shards of a benchmark run.
+//
+// benchmerge can merge inputs larger than memory by spilling sorted
+// results to temporary files. The -mem flag controls how much memory
+// it uses before spilling.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"golang.org/x/perf/benchfmt"
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: benchmerge [flags] [inputs...]
+
+benchmerge reads Go benchmark results from input files, merges them,
+and writes them to stdout ordered by file configuration and benchmark
+name. If no inputs are provided, it reads from stdin.
+
+`)
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetPrefix("benchmerge: ")
+	log.SetFlags(0)
+
+	flagDedup := flag.Bool("dedup", false, "drop results identical to another result")
+	flagMem := flag.Int("mem", benchfmt.DefaultMergeMemory>>20, "buffer up to `MiB` of results in memory before spilling to temporary files")
+	flagTmp := flag.String("tmpdir", "", "write temporary files to `dir`")
+	flag.Usage = usage
+	flag.Parse()
+
+	if *flagMem <= 0 {
+		log.Fatal("-mem must be positive")
+	}
+
+	merger := benchfmt.Merger{
+		MaxMemory: *flagMem << 20,
+		TempDir:   *flagTmp,
+		Dedup:     *flagDedup,
+	}
+	defer merger.Close()
+
+	files := benchfmt.Files{Paths: flag.Args(), AllowStdin: true}
+	for files.Scan() {
+		rec := files.Result()
+		if err, ok := rec.(*benchfmt.SyntaxError); ok {
+			// Non-fatal result parse error. Warn
+			// but keep going.
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		if err := merger.Add(rec); err != nil {
+			if _, ok := err.(*benchfmt.SyntaxError); ok {
+				// Conflicting unit metadata. Warn but
+				// keep going.
+				fmt.Fprintln(os.Stderr, err)
+				continue
+			}
+			fail(&merger, err)
+		}
+	}
+	if err := files.Err(); err != nil {
+		fail(&merger, err)
+	}
+
+	// The merged results combine all input files, so ignore .file
+	// when deciding whether to start a new configuration block.
+	writer := benchfmt.NewWriterOptions(os.Stdout, benchfmt.WriterOptions{IgnoreInternalConfig: true})
+	for merger.Scan() {
+		if err := writer.Write(merger.Result()); err != nil {
+			fail(&merger, fmt.Errorf("writing output: %w", err))
+		}
+	}
+	if err := merger.Err(); err != nil {
+		fail(&merger, err)
+	}
+}
+
+// fail removes any temporary files and exits with err.
+func fail(merger *benchfmt.Merger, err error) {
+	merger.Close()
+	log.Fatal(err)
+}
@@ -8,7 +8,9 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	// "github.com/dr2chase/debug-server/debug_client"
 	"golang.org/x/perf/benchfmt"
@@ -23,21 +25,40 @@ func main() {
 
 	var delta bool = false
 	var change bool = false
+	var changeBetter bool = false
 	var values bool = true
 	var csv bool = true
 	var logScale bool = true
 	var boring bool = false
+	var changes bool = false
+	var geomean bool = false
+	var penalty float64 = 0
+	var blame = ""
+	var alerts bool = false
+	var alertOpts benchseries.AlertOptions
+	var acks []benchseries.Acknowledgement
 
 	var pngDir = ""
 	var svgDir = ""
 	var pdfDir = ""
+	var htmlOut = ""
 
 	var jsonOut = ""
 	var jsonIn = ""
+	var merge = "replace"
+	var estimator = "bootstrap"
+	var dbFile = ""
+	var preset = ""
+	var configFile = ""
+	var query benchseries.StoreQuery
 
 	confidence := 0.95
+	changeConfidence := 0.95
 	threshold := 0.02
 
+	flag.StringVar(&preset, "preset", preset, "Start from the builder options `preset` "+strings.Join(benchseries.PresetNames(), " or ")+" instead of bent")
+	flag.StringVar(&configFile, "config", configFile, "Read options from this `file` of \"flag: value\" lines, which may also select a \"preset\"; command-line flags take precedence")
+
 	flag.StringVar(&bo.Series, "series", bo.Series, "Specify the benchmarking key for the series x-axis")
 	flag.StringVar(&bo.Experiment, "experiment", bo.Experiment, "Specify the experient-time key common to trials in an experiment")
 
@@ -49,27 +70,90 @@ func main() {
 	flag.StringVar(&bo.DenominatorHash, "denominator-hash", bo.DenominatorHash, "Key for hash id of denominators (can be same as numerator-hash)")
 
 	flag.StringVar(&bo.Filter, "filter", bo.Filter, "Apply this filter to incoming benchmarks")
+	flag.StringVar(&bo.Table, "table", bo.Table, "Specify the benchmark keys to group tables by, in addition to .unit")
+	flag.StringVar(&bo.Ignore, "ignore", bo.Ignore, "Specify the benchmark keys to ignore entirely")
 
 	flag.BoolVar(&csv, "csv", csv, "Write the series in CSV form")
 
 	flag.BoolVar(&delta, "delta", delta, "Include the plus-or-minus range in the spreadsheet view")
 	flag.BoolVar(&change, "change", change, "Include a change-detected column")
+	flag.BoolVar(&changeBetter, "change-better", changeBetter, "Include a change-detected column oriented by the unit's better direction, so regressions are negative")
 	flag.BoolVar(&values, "values", values, "Include values columns")
 	flag.BoolVar(&logScale, "log", logScale, "Use a log scale in the chart")
 
 	flag.StringVar(&pngDir, "png", pngDir, "Directory to write png chart(s) into")
 	flag.StringVar(&pdfDir, "pdf", pdfDir, "Directory to write pdf chart(s) into")
 	flag.StringVar(&svgDir, "svg", svgDir, "Directory to write svg chart(s) into")
+	flag.StringVar(&htmlOut, "html", htmlOut, "Write an interactive HTML dashboard to this file")
 
 	flag.StringVar(&jsonOut, "jo", jsonOut, "Save benchmarking summary in this json file")
 	flag.StringVar(&jsonIn, "ji", jsonIn, "Read benchmarking summary from this json file")
-
+	flag.StringVar(&dbFile, "db", dbFile, "Read benchmarking summaries from and save them to this SQLite database")
+	flag.StringVar(&query.Unit, "db-unit", query.Unit, "Only read this unit from the -db database")
+	flag.StringVar(&query.Benchmark, "db-benchmark", query.Benchmark, "Only read this benchmark from the -db database")
+	flag.StringVar(&query.Since, "since", query.Since, "Only read series points at or after this RFC3339 date from the -db database")
+	flag.StringVar(&query.Until, "until", query.Until, "Only read series points at or before this RFC3339 date from the -db database")
+	flag.StringVar(&merge, "merge", merge, "How to merge results that overlap the -ji or -db summary: `policy` replace (newest wins), new (new results win), combine (bootstrap all raw values together, saved by -keep-values), or error")
+	flag.BoolVar(&bo.KeepValues, "keep-values", bo.KeepValues, "Save the raw measurements in the -jo or -db summary, so later runs can use -merge combine")
+
+	flag.StringVar(&estimator, "estimator", estimator, "How to summarize each comparison: `method` bootstrap (median ratio), hodges-lehmann (median pairwise shift), or mean (mean ratio with t-interval); units with assume=exact metadata are always summarized exactly")
 	flag.Float64Var(&confidence, "confidence", confidence, "width of confidence interval")
-	flag.Float64Var(&threshold, "threshold", threshold, "threshold for 'it changed' for exact metrics")
+	flag.Float64Var(&threshold, "threshold", threshold, "threshold for 'it changed' for exact units (assume=exact metadata) and empty confidence intervals")
 	flag.BoolVar(&boring, "boring", boring, "include the boring parts of the history")
+	flag.BoolVar(&geomean, "geomean", geomean, "Add a \"geomean\" benchmark summarizing all benchmarks at each point to the output; it is not saved by -db or merged from -ji")
+	flag.BoolVar(&changes, "changes", changes, "Write a report of detected change points instead of CSV")
+	flag.Float64Var(&changeConfidence, "change-confidence", changeConfidence, "Minimum confidence of the change points reported by -blame")
+	flag.StringVar(&blame, "blame", blame, "Write a report of significant step changes and the commit ranges to bisect, in `format` text, markdown, or json, instead of CSV")
+	flag.BoolVar(&alerts, "alerts", alerts, "Write JSON records of unacknowledged regressions persisting through the latest points instead of CSV")
+	flag.IntVar(&alertOpts.Window, "alert-window", 10, "Number of points before a regression in the -alerts baseline")
+	flag.IntVar(&alertOpts.Confirmations, "alert-confirm", 3, "Number of consecutive regressed points required by -alerts")
+	flag.Float64Var(&alertOpts.Threshold, "alert-threshold", 0.02, "Minimum relative regression from the baseline reported by -alerts")
+	flag.Func("ack", "Acknowledge alerts with onset at `benchmark@date` or in benchmark@date..date (any benchmark if empty), remembered in the -jo or -db summary; may be repeated", func(s string) error {
+		a, err := benchseries.ParseAcknowledgement(s)
+		if err != nil {
+			return err
+		}
+		acks = append(acks, a)
+		return nil
+	})
+	flag.Float64Var(&penalty, "penalty", penalty, "Change point penalty; larger finds fewer changes (0 means default)")
+
+	// The preset and config file are applied before parsing the
+	// command line, so that its flags take precedence.
+	if preset, configFile := configFlags(os.Args[1:]); preset != "" || configFile != "" {
+		applyConfig(bo, preset, configFile)
+	}
 
 	flag.Parse()
 
+	if len(acks) > 0 && jsonOut == "" && dbFile == "" {
+		fail("-ack requires -jo or -db to remember the acknowledgements\n")
+	}
+
+	var dupeHow int
+	switch merge {
+	case "replace":
+		dupeHow = benchseries.DUPE_REPLACE
+	case "new":
+		dupeHow = benchseries.DUPE_NEW
+	case "combine":
+		dupeHow = benchseries.DUPE_COMBINE
+	case "error":
+		dupeHow = benchseries.DUPE_ERROR
+	default:
+		fail("-merge must be replace, new, combine, or error\n")
+	}
+
+	switch estimator {
+	case "bootstrap":
+	case "hodges-lehmann":
+		bo.Estimator = benchseries.HodgesLehmann
+	case "mean":
+		bo.Estimator = benchseries.MeanT
+	default:
+		fail("-estimator must be bootstrap, hodges-lehmann, or mean\n")
+	}
+
 	bo.Warn = warn
 	seriesBuilder, err := benchseries.NewBuilder(bo)
 
@@ -97,8 +181,25 @@ func main() {
 		f.Close()
 	}
 
+	// Optionally read pre-existing comparisons from a database.
+	var store benchseries.Store
+	if dbFile != "" {
+		if jsonIn != "" {
+			fail("-ji and -db cannot be used together\n")
+		}
+		store, err = benchseries.OpenSQLiteStore(dbFile)
+		if err != nil {
+			fail("Could not open database (flag -db), %v\n", err)
+		}
+		defer store.Close()
+		comparisons, err = store.Get(query)
+		if err != nil {
+			fail("Could not read database (flag -db), %v\n", err)
+		}
+	}
+
 	// Rearrange into comparisons (not yet doing the statistical work)
-	comparisons, err = seriesBuilder.AllComparisonSeries(comparisons, benchseries.DUPE_REPLACE)
+	comparisons, err = seriesBuilder.AllComparisonSeries(comparisons, dupeHow)
 	if err != nil {
 		fail("Error building comparison series: %v", err)
 	}
@@ -141,6 +242,15 @@ func main() {
 	// Bootstrap and add (missing, if some already supplied by JSON) summaries.
 	for _, c := range comparisons {
 		c.AddSummaries(confidence, 1000)
+		for _, a := range acks {
+			c.Acknowledge(a)
+		}
+		if geomean {
+			// The geomean depends on which benchmarks were read, so
+			// it's derived, and neither saved by -db nor merged when
+			// read back with -ji.
+			c.AddGeomean(confidence, 1000)
+		}
 	}
 
 	// Generate some output.  Options include CSV, JSON, PNG, perhaps also PDF and SVG.
@@ -153,10 +263,19 @@ func main() {
 	if change {
 		options |= benchseries.CSV_CHANGE_HEU | benchseries.CSV_CHANGE_KS
 	}
+	if changeBetter {
+		options |= benchseries.CSV_CHANGE_BETTER
+	}
 	if values {
 		options |= benchseries.CSV_VALUES
 	}
 
+	if store != nil {
+		if err := store.Put(comparisons); err != nil {
+			fail("Could not update database (flag -db), %v\n", err)
+		}
+	}
+
 	if jsonOut != "" {
 		w, err := os.Create(jsonOut)
 		if err != nil {
@@ -168,16 +287,129 @@ func main() {
 		w.Close()
 	}
 
-	if csv {
+	if blame != "" {
+		var write func(io.Writer, []*benchseries.StepChange) error
+		switch blame {
+		case "text":
+			write = benchseries.WriteStepChangesText
+		case "markdown":
+			write = benchseries.WriteStepChangesMarkdown
+		case "json":
+			write = benchseries.WriteStepChangesJSON
+		default:
+			fail("-blame must be text, markdown, or json\n")
+		}
+		var scs []*benchseries.StepChange
+		for _, comparison := range comparisons {
+			scs = append(scs, comparison.StepChanges(penalty, changeConfidence, threshold)...)
+		}
+		if err := write(os.Stdout, scs); err != nil {
+			fail("Error writing report: %v\n", err)
+		}
+	} else if alerts {
+		var as []*benchseries.Alert
+		for _, comparison := range comparisons {
+			for _, a := range comparison.Alerts(alertOpts) {
+				if !a.Suppressed {
+					as = append(as, a)
+				}
+			}
+		}
+		if err := benchseries.WriteAlertsJSON(os.Stdout, as); err != nil {
+			fail("Error writing alerts: %v\n", err)
+		}
+	} else if changes {
+		for _, comparison := range comparisons {
+			comparison.ChangesReport(os.Stdout, penalty, threshold)
+		}
+	} else if csv {
 		for _, comparison := range comparisons {
 			comparison.ToCsvBootstrapped(os.Stdout, options, threshold)
 		}
 	}
+	if htmlOut != "" {
+		w, err := os.Create(htmlOut)
+		if err != nil {
+			fail("Could not create HTML output file (flag -html), %v", err)
+		}
+		if err := benchseries.WriteHTML(w, comparisons, penalty); err != nil {
+			fail("Error writing HTML output: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			fail("Error writing HTML output: %v", err)
+		}
+	}
 	if pngDir != "" || pdfDir != "" || svgDir != "" {
 		benchseries.Chart(comparisons, pngDir, pdfDir, svgDir, logScale, threshold, boring)
 	}
 }
 
+// configFlags returns the values of the -preset and -config flags in
+// args, which must be known before the rest of the command line is
+// parsed. Like flag.Parse, it stops at the first non-flag argument.
+func configFlags(args []string) (preset, configFile string) {
+	for len(args) > 0 {
+		arg := args[0]
+		args = args[1:]
+		if len(arg) < 2 || arg[0] != '-' || arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(arg[1:], "-"), "=")
+		if f := flag.Lookup(name); f != nil && !hasValue && len(args) > 0 {
+			// Non-boolean flags take the next argument as their value.
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+				value, args = args[0], args[1:]
+			}
+		}
+		switch name {
+		case "preset":
+			preset = value
+		case "config":
+			configFile = value
+		}
+	}
+	return preset, configFile
+}
+
+// applyConfig replaces bo with the named preset and then applies the
+// settings in configFile, if any, including output options, which are
+// set as flags. It's called before flag.Parse, so flags set on the
+// command line take precedence.
+func applyConfig(bo *benchseries.BuilderOptions, preset, configFile string) {
+	if preset == "" {
+		preset = "bent"
+	}
+	loaded, err := benchseries.PresetBuilderOptions(preset)
+	if err != nil {
+		fail("-preset: %v\n", err)
+	}
+	var rest []benchseries.ConfigSetting
+	if configFile != "" {
+		f, err := os.Open(configFile)
+		if err != nil {
+			fail("Could not read config file (flag -config), %v\n", err)
+		}
+		loaded, rest, err = benchseries.LoadConfig(f, configFile, preset)
+		f.Close()
+		if err != nil {
+			fail("%v\n", err)
+		}
+	}
+	*bo = *loaded
+
+	for _, s := range rest {
+		cerr := func(err error) error {
+			return &benchseries.ConfigError{File: configFile, Line: s.Line, Key: s.Key, Err: err}
+		}
+		if s.Key == "config" || flag.Lookup(s.Key) == nil {
+			fail("%v\n", cerr(fmt.Errorf("unknown key")))
+		}
+		if err := flag.Set(s.Key, s.Value); err != nil {
+			fail("%v\n", cerr(err))
+		}
+	}
+}
+
 func fail(format string, args ...interface{}) {
 	fmt.Fprintf(os.Stderr, format, args...)
 	os.Exit(1)
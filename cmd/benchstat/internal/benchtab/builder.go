@@ -12,6 +12,7 @@ import (
 	"io"
 	"math"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 
@@ -173,6 +174,7 @@ func (b *Builder) ToTables(opts TableOpts) *Tables {
 			Cols:       colKeys,
 			Cells:      make(map[TableKey]*TableCell),
 		}
+		table.Warnings = sparseWarnings(rowKeys, colKeys, cTable.cells)
 		tables = append(tables, table)
 
 		// Create all TableCells and fill their Samples. This
@@ -255,6 +257,49 @@ func mapKeys(m map[benchproc.Key]struct{}) []benchproc.Key {
 	return keys
 }
 
+// sparseWarnings returns warnings explaining why a table is sparse,
+// if it is. Very sparse tables are usually caused by a row field and a
+// column field that are correlated, such as a column for each package
+// when each benchmark name appears in only one package.
+func sparseWarnings(rows, cols []benchproc.Key, cells map[TableKey]*builderCell) []error {
+	if len(rows) < 2 || len(cols) < 2 || 2*len(cells) > len(rows)*len(cols) {
+		return nil
+	}
+
+	rowFields := make(map[*benchproc.Field]bool)
+	for _, f := range rows[0].Projection().FlattenedFields() {
+		rowFields[f] = true
+	}
+	var obs [][]benchproc.Key
+	for k := range cells {
+		obs = append(obs, []benchproc.Key{k.Row, k.Col})
+	}
+	// Sort the observations so warnings are deterministic.
+	sort.Slice(obs, func(i, j int) bool {
+		if obs[i][0] != obs[j][0] {
+			return obs[i][0].Less(obs[j][0])
+		}
+		return obs[i][1].Less(obs[j][1])
+	})
+
+	// Report only dependencies between a row field and a column
+	// field, and only one per determined field.
+	var warnings []error
+	done := make(map[*benchproc.Field]bool)
+	for _, d := range benchproc.FunctionalDependencies(obs) {
+		if rowFields[d.From] == rowFields[d.To] || done[d.To] {
+			continue
+		}
+		done[d.To] = true
+		from, to, dir := "row", "column", "-row"
+		if !rowFields[d.From] {
+			from, to, dir = "column", "row", "-col"
+		}
+		warnings = append(warnings, fmt.Errorf("table is sparse (%d of %d cells): %s key %s determines %s key %s; consider moving %s to %s", len(cells), len(rows)*len(cols), from, d.From.Name, to, d.To.Name, d.To.Name, dir))
+	}
+	return warnings
+}
+
 func summarizeCell(cCell *builderCell, cell *TableCell, assumption benchmath.Assumption, confidence float64) {
 	cell.Summary = assumption.Summary(cell.Sample, confidence)
 
@@ -50,6 +50,10 @@ type Table struct {
 
 	// SummaryLabel is the label for the summary row.
 	SummaryLabel string
+
+	// Warnings is a list of warnings for this table as a whole,
+	// such as that it is very sparse.
+	Warnings []error
 }
 
 // TableKey is a map key used to index a single cell in a Table.
@@ -285,6 +289,11 @@ func (t *Table) ToText(w io.Writer, color bool) error {
 			}
 		}
 	}
+	for _, msg := range t.Warnings {
+		if _, err := fmt.Fprintf(w, "warning: %s\n", msg); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -355,6 +364,9 @@ func (t *Table) ToCSV(o *csv.Writer, startRow int, warnings io.Writer) (rowCount
 		}
 	}
 
+	// Emit table-wide warnings.
+	warn(t.Warnings)
+
 	// Emit column configurations header.
 	colFields := t.Cols[0].Projection().FlattenedFields()
 	for _, field := range colFields {
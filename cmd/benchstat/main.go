@@ -147,10 +147,17 @@
 //	key:"value"   - Same, but value is a double-quoted Go string that
 //	                may contain spaces or other special characters.
 //	"key":value   - Keys may also be double-quoted.
+//	key:glob*     - Match if key matches a glob pattern, where "*" matches
+//	                any string and "?" matches any character.
 //	key:/regexp/  - Match if key matches a regular expression.
+//	key:/regexp/i - Same, but case-insensitive.
 //	key:(val1 OR val2 OR ...)
 //	              - Short-hand for key:val1 OR key:val2. Values may be
-//	                double-quoted strings or regexps.
+//	                double-quoted strings, globs, or regexps.
+//	key<value     - Match if key is less than value, comparing numerically
+//	key<=value      or as versions. Likewise for <=, >, and >=.
+//	key>value
+//	key>=value
 //	*             - Match everything.
 //
 // These terms can be combined into larger expressions as follows:
@@ -169,6 +176,8 @@
 //	/{name-key}   - Per-benchmark sub-name configuration key
 //	{file-key}    - File-level configuration key
 //	.unit         - The name of a unit for a particular metric
+//	.value        - The value of a particular metric, in tidied units (e.g., .value>1ms)
+//	.iters        - The iteration count of a benchmark
 //
 // For example, the following matches benchmarks with "/format=json"
 // in the sub-name keys with file-level configuration "goos" equal to
@@ -291,6 +300,42 @@
 // that the benchmarks it grouped together vary in a hidden dimension.
 // If this really were our intent, we could -ignore .fullname.
 //
+// benchstat also warns if a table is very sparse, which usually means
+// a row key and a column key are correlated. For example, if each
+// benchmark is in only one package, then "-col pkg" produces a table
+// where each row has a value in only one column. In this case,
+// benchstat reports which key determines the other and suggests moving
+// the determined key to the other projection:
+//
+//	warning: table is sparse (4 of 8 cells): row key .fullname determines column key pkg; consider moving pkg to -row
+//
+// # Name schemas
+//
+// Not all benchmarks use key=value sub-benchmark names. The
+// -name-schema flag assigns keys to positional name parts, so they can
+// be used like any other sub-name key in filters, projections, and
+// derived units. For example, with -name-schema /size/codec, the
+// benchmark "BenchmarkDecode/1024/gzip" has "/size" 1024 and "/codec"
+// gzip, just like "BenchmarkDecode/size=1024/codec=gzip". The schema
+// may also be a regular expression beginning with "^" whose named
+// groups become keys. Benchmark names are not changed.
+// For details of the name schema syntax, see
+// https://pkg.go.dev/golang.org/x/perf/benchproc/syntax#hdr-Name_schemas.
+//
+// # Derived units
+//
+// The -derive flag adds a measurement to each benchmark result that is
+// computed from its other measurements and keys. For example, if
+// benchmarks have a "/size" sub-name key giving the number of elements
+// processed, the following reports the time per element:
+//
+//	benchstat -derive "ns/elem=sec/op / /size" old.txt new.txt
+//
+// The -derive flag may be repeated. Derived measurements are computed
+// before filtering, so they can be selected with .unit. For details of
+// the derived unit syntax, see
+// https://pkg.go.dev/golang.org/x/perf/benchproc/syntax#hdr-Derived_units.
+//
 // # Sorting
 //
 // By default, benchstat sorts each dimension according to the order
@@ -298,14 +343,28 @@
 // be overridden in each projection using the following syntax:
 //
 // {key}@{order} - specifies one of the built-in named sort orders.
-// This can be "alpha" or "num" for alphabetic or numeric sorting.
-// "num" understands basic use of metric and IEC prefixes like "2k"
-// and "1Mi".
+// This can be "alpha" or "num" for alphabetic or numeric sorting,
+// "semver" or "goversion" for version sorting (understanding values
+// like "go1.21.3" and "devel go1.23-abcdef"), or "time" for sorting
+// RFC 3339 or compact "20211229T213212" times. "num" understands
+// basic use of metric and IEC prefixes like "2k" and "1Mi".
+//
+// {key}@git({dir}) - orders commit hashes by their position in the
+// history of the git repository in directory dir (or the current
+// directory if dir is omitted). Hashes may be abbreviated.
 //
 // {key}@({value} {value} ...) - specifies a fixed value order for
 // key. It also specifies a filter: if key has a value that isn't any
 // of the specified values, the result is filtered out.
 //
+// Before sorting, a projection can also rewrite the values of a key
+// using {key}@alias({value}={alias} ...) to rename specific values, or
+// {key}@re(/{regexp}/ ...) to replace values with the part matched by
+// a regexp (or its first capturing group). For example,
+// "toolchain@alias(abc123=old def456=new)@(old new)" labels two
+// commit hashes and orders them. For details, see
+// https://pkg.go.dev/golang.org/x/perf/benchproc/syntax#hdr-Projections.
+//
 // For example, we can use a fixed order to compare the improvement of
 // json over gob rather than the other way around:
 //
@@ -412,14 +471,8 @@ import (
 
 // TODO: -unit flag.
 
-// TODO: Support sorting by commit order.
-
 // TODO: Add some quick usage examples to the -h output?
 
-// TODO: If the projection results in a very sparse table, that's
-// usually the result of correlated keys. Can we detect that and
-// suggest fixes?
-
 func main() {
 	if err := benchstat(os.Stdout, os.Stderr, os.Args[1:]); err != nil {
 		fmt.Fprintf(os.Stderr, "benchstat: %s\n", err)
@@ -448,6 +501,9 @@ For details, see https://pkg.go.dev/golang.org/x/perf/cmd/benchstat.
 	flagCol := flags.String("col", ".file", "split results into columns by distinct values of `projection`")
 	flagIgnore := flags.String("ignore", "", "ignore variations in `keys`")
 	flagFilter := flags.String("filter", "*", "use only benchmarks matching benchfilter `query`")
+	flagNameSchema := flags.String("name-schema", "", "assign keys to positional sub-benchmark name parts using `schema`, such as /size/codec")
+	var derive benchproc.Derivations
+	flags.Var(&derive, "derive", "add a measurement derived from other measurements by `unit=expr`; may be repeated")
 	flags.Float64Var(&thresholds.CompareAlpha, "alpha", thresholds.CompareAlpha, "consider change significant if p < `α`")
 	// TODO: Support -confidence none to disable CI column? This
 	// would be equivalent to benchstat v1's -norange for CSV.
@@ -460,12 +516,25 @@ For details, see https://pkg.go.dev/golang.org/x/perf/cmd/benchstat.
 		os.Exit(2)
 	}
 
-	filter, err := benchproc.NewFilter(*flagFilter)
+	var nameSchema *benchproc.NameSchema
+	if *flagNameSchema != "" {
+		var err error
+		nameSchema, err = benchproc.NewNameSchema(*flagNameSchema)
+		if err != nil {
+			return fmt.Errorf("parsing -name-schema: %s", err)
+		}
+		derive, err = derive.WithNameSchema(nameSchema)
+		if err != nil {
+			return fmt.Errorf("parsing -derive: %s", err)
+		}
+	}
+
+	filter, err := nameSchema.NewFilter(*flagFilter)
 	if err != nil {
 		return fmt.Errorf("parsing -filter: %s", err)
 	}
 
-	var parser benchproc.ProjectionParser
+	parser := benchproc.ProjectionParser{NameSchema: nameSchema}
 	var parseErr error
 	mustParse := func(name, val string, unit bool) *benchproc.Projection {
 		var proj *benchproc.Projection
@@ -514,6 +583,7 @@ For details, see https://pkg.go.dev/golang.org/x/perf/cmd/benchstat.
 			// but keep going.
 			fmt.Fprintln(wErr, rec)
 		case *benchfmt.Result:
+			derive.Apply(rec)
 			if ok, err := filter.Apply(rec); !ok {
 				if err != nil {
 					// Print the reason we rejected this result.
@@ -29,6 +29,23 @@ func TestCRC(t *testing.T) {
 	golden(t, "crcSizeVsPoly", "-filter", "/align:0", "-row", "/size", "-col", "/poly", "crc-new.txt")
 }
 
+func TestDerive(t *testing.T) {
+	// Compute time per byte from the /size key and select just
+	// the derived unit.
+	golden(t, "crcDerive", "-derive", "ns/B=sec/op / /size", "-filter", "/align:0 .unit:ns/B", "-row", "/size", "-col", "/poly", "crc-new.txt")
+}
+
+func TestNameSchema(t *testing.T) {
+	// Name the positional sub-benchmark part and group by it.
+	golden(t, "nameSchema", "-name-schema", "/prec", "-filter", "/prec>=100", "-row", "/prec", "-col", "note", "issue19634.txt")
+}
+
+func TestSparse(t *testing.T) {
+	// Each benchmark is in only one package, so a column per
+	// package makes a sparse table.
+	golden(t, "sparse", "-col", "pkg", "sparse.txt")
+}
+
 func TestUnits(t *testing.T) {
 	// Test unit metadata. This tests exact assumptions and
 	// warnings for inexact distributions.
@@ -0,0 +1,78 @@
+// Copyright 2022 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// benchvet checks that Go benchmark results follow the benchmark
+// format specification (https://golang.org/design/14313-benchmark-format)
+// and reports any problems. If no inputs are provided, it reads from
+// stdin.
+//
+// benchvet is stricter than the tools that consume benchmark results,
+// which skip lines they don't understand. For example, it reports
+// lines that look like mistyped configuration lines, zero iteration
+// counts, misspelled or scaled units, duplicate units on one line,
+// unknown unit metadata, and benchmarks whose configuration keys
+// change between runs. See
+// benchfmt.Validate for the full list of checks.
+//
+// benchvet exits with status 1 if it finds any problems.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"golang.org/x/perf/benchfmt"
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: benchvet [inputs...]
+
+benchvet checks that Go benchmark results follow the benchmark format
+specification and reports any problems. If no inputs are provided, it
+reads from stdin.
+`)
+	flag.PrintDefaults()
+}
+
+func main() {
+	log.SetPrefix("benchvet: ")
+	log.SetFlags(0)
+
+	flag.Usage = usage
+	flag.Parse()
+
+	paths := flag.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	bad := false
+	for _, path := range paths {
+		var f *os.File
+		if path == "-" {
+			f = os.Stdin
+		} else {
+			var err error
+			f, err = os.Open(path)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		errs, err := benchfmt.Validate(f, path)
+		if f != os.Stdin {
+			f.Close()
+		}
+		for _, e := range errs {
+			fmt.Println(e)
+			bad = true
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if bad {
+		os.Exit(1)
+	}
+}